@@ -13,7 +13,10 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"time"
 
@@ -21,11 +24,17 @@ import (
 	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
 	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
 	controller "github.com/snapp-incubator/contour-admission-webhook/internal/controller/httpproxy"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/controller/ingressclass"
 	"github.com/snapp-incubator/contour-admission-webhook/internal/webhook"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 var (
@@ -34,6 +43,7 @@ var (
 )
 
 func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(contourv1.AddToScheme(scheme))
 }
 
@@ -53,15 +63,57 @@ func main() {
 		os.Exit(1)
 	}
 
+	cfg := config.GetConfig()
+
+	utils.SetDefaultIngressClass(cfg.DefaultIngressClass)
+
 	logger.Info("initializing cache")
 
-	cfg := config.GetConfig()
+	var cacheStore cache.Cache
+
+	switch cfg.Cache.Backend {
+	case "", config.CacheBackendMemory:
+		cacheStore = cache.NewCache(time.Duration(cfg.Cache.CleanUpIntervalSecond) * time.Second)
+	case config.CacheBackendRedis:
+		redisCache, err := cache.NewRedisCache(cfg.Cache.Redis)
+		if err != nil {
+			logger.Error(err, "unable to initialize the cache")
+
+			os.Exit(1)
+		}
+
+		cacheStore = redisCache
+	default:
+		logger.Error(fmt.Errorf("unknown cache backend %s", cfg.Cache.Backend), "unable to initialize the cache")
+
+		os.Exit(1)
+	}
 
-	cache := cache.NewCache(time.Duration(cfg.Cache.CleanUpIntervalSecond) * time.Second)
+	// The quarantines must survive restarts, otherwise any namespace could take a released FQDN over after a restart.
+	if cfg.Cache.QuarantineSecond > 0 && cfg.Cache.Backend != config.CacheBackendRedis && !cfg.Cache.Snapshot.Enabled {
+		logger.Error(errors.New("quarantineSecond requires the redis backend or the cache snapshot"), "unable to initialize the cache")
+
+		os.Exit(1)
+	}
+
+	if cfg.Cache.Snapshot.Enabled {
+		// The snapshot is loaded before the informers start, so the entries reserved before the restart are kept.
+		loaded, err := cache.LoadSnapshot(cacheStore, cfg.Cache.Snapshot.Path)
+		if err != nil {
+			logger.Error(err, "unable to load the cache snapshot")
+
+			os.Exit(1)
+		}
+
+		logger.Info("cache snapshot is loaded", "path", cfg.Cache.Snapshot.Path, "count", loaded)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:         scheme,
 		LeaderElection: false,
+		// The TLS secrets validated by the webhook are read directly from the API server, so the secrets of the whole
+		// cluster are neither watched nor held in memory.
+		Client: client.Options{Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}}}},
 	})
 	if err != nil {
 		logger.Error(err, "unable to create manager")
@@ -69,7 +121,15 @@ func main() {
 		os.Exit(1)
 	}
 
-	reconcilerExtended := controller.NewReconcilerExtended(mgr, cache)
+	if cfg.Cache.Stateless {
+		if err = controller.SetupFqdnIndex(context.Background(), mgr); err != nil {
+			logger.Error(err, "unable to set up the fqdn field index")
+
+			os.Exit(1)
+		}
+	}
+
+	reconcilerExtended := controller.NewReconcilerExtended(mgr, cacheStore)
 
 	if err = reconcilerExtended.SetupWithManager(mgr); err != nil {
 		logger.Error(err, "unable to set up the controller with the manager", "controller", "httpproxy")
@@ -77,6 +137,63 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The webhook is not ready until the valid ingressClassNames are discovered, otherwise all the requests are denied.
+	var ready func() bool
+
+	if cfg.IngressClassDiscovery.Enabled {
+		if len(cfg.IngressClasses) > 0 {
+			logger.Info("ingressclass discovery is disabled as ingressClasses is set")
+		} else {
+			ingressClassReconciler := ingressclass.NewReconciler(mgr, cacheStore, cfg.IngressClassDiscovery.ControllerName)
+
+			if err = ingressClassReconciler.SetupWithManager(mgr); err != nil {
+				logger.Error(err, "unable to set up the controller with the manager", "controller", "ingressclass")
+
+				os.Exit(1)
+			}
+
+			ready = ingressClassReconciler.Synced
+		}
+	}
+
+	if cfg.Cache.Snapshot.Enabled || cfg.Cache.ResyncIntervalSecond > 0 {
+		if err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			if !mgr.GetCache().WaitForCacheSync(ctx) {
+				return errors.New("failed to wait for the informers to sync")
+			}
+
+			// Reconcile the loaded entries against the HTTPProxy objects once the informers are synced.
+			if cfg.Cache.Snapshot.Enabled {
+				corrections, err := reconcilerExtended.ResyncCache(ctx)
+				if err != nil {
+					return err
+				}
+
+				logger.Info("cache snapshot is reconciled", "corrections", corrections)
+			}
+
+			if cfg.Cache.ResyncIntervalSecond == 0 {
+				return nil
+			}
+
+			return reconcilerExtended.StartResync(ctx, time.Duration(cfg.Cache.ResyncIntervalSecond)*time.Second)
+		})); err != nil {
+			logger.Error(err, "unable to add the cache resync to the manager")
+
+			os.Exit(1)
+		}
+	}
+
+	if cfg.Cache.Snapshot.Enabled {
+		if err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return cache.StartSnapshotter(ctx, cacheStore, cfg.Cache.Snapshot.Path, time.Duration(cfg.Cache.Snapshot.IntervalSecond)*time.Second)
+		})); err != nil {
+			logger.Error(err, "unable to add the cache snapshotter to the manager")
+
+			os.Exit(1)
+		}
+	}
+
 	errChan := make(chan error)
 
 	ctx := ctrl.SetupSignalHandler()
@@ -92,7 +209,7 @@ func main() {
 	}()
 
 	// This call is non-blocking.
-	webhookStoppedCh, webhookListenerStoppedCh := webhook.Setup(cache)
+	webhookStoppedCh, webhookListenerStoppedCh := webhook.Setup(cacheStore, mgr.GetClient(), ready)
 
 	select {
 	case err := <-errChan:
@@ -13,6 +13,9 @@
 package cache
 
 import (
+	"context"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,24 +28,57 @@ var (
 	logger = ctrl.Log.WithName("cache")
 )
 
-type Cache struct {
-	fqdnMap         map[string]*element // map[ingressClassName/FQDN]*element
+// MemoryCache is the in-memory Cache implementation.
+type MemoryCache struct {
+	fqdnMap         map[string]*element                          // map[ingressClassName/FQDN]*element
+	ownerIndex      map[types.NamespacedName]map[string]struct{} // map[owner]set[key]
+	namespaceIndex  map[string]map[string]struct{}               // map[namespace]set[key]
+	expiries        expiryHeap                                   // The expiring elements ordered by ExpiresAt
 	mu              *sync.RWMutex
 	cleanUpTicker   *time.Ticker // Ticker
 	CleanUpStopChan chan bool    // Channel for stopping the ticker
+	watchers        map[chan Event]struct{}
+	watchersMu      *sync.RWMutex
 }
 
+var _ Cache = &MemoryCache{}
+
 type element struct {
-	Value     *types.NamespacedName
-	ExpiresAt int64
+	Value       *types.NamespacedName
+	ExpiresAt   int64
+	ModifiedAt  int64
+	Claimants   []types.NamespacedName
+	Quarantined bool
+	key         string
+	heapIndex   int // The index in the expiry heap, -1 if not scheduled
 }
 
-func NewCache(cleanUpInterval time.Duration) *Cache {
-	cache := &Cache{
+// entry returns a copy of the element as an Entry.
+func (e *element) entry() Entry {
+	entry := Entry{Key: e.key, ExpiresAt: e.ExpiresAt, ModifiedAt: e.ModifiedAt, Quarantined: e.Quarantined}
+
+	if len(e.Claimants) > 0 {
+		entry.Claimants = slices.Clone(e.Claimants)
+	}
+
+	if e.Value != nil {
+		entry.Owner = *e.Value
+	}
+
+	return entry
+}
+
+func NewCache(cleanUpInterval time.Duration) *MemoryCache {
+	cache := &MemoryCache{
 		fqdnMap:         make(map[string]*element),
+		ownerIndex:      make(map[types.NamespacedName]map[string]struct{}),
+		namespaceIndex:  make(map[string]map[string]struct{}),
+		expiries:        make(expiryHeap, 0),
 		mu:              &sync.RWMutex{},
 		cleanUpTicker:   time.NewTicker(cleanUpInterval),
 		CleanUpStopChan: make(chan bool),
+		watchers:        make(map[chan Event]struct{}),
+		watchersMu:      &sync.RWMutex{},
 	}
 
 	cache.StartCleaner()
@@ -50,17 +86,79 @@ func NewCache(cleanUpInterval time.Duration) *Cache {
 	return cache
 }
 
-func (c *Cache) Set(key string, value *types.NamespacedName, expirationUnixTime int64) {
+func (c *MemoryCache) Set(key string, value *types.NamespacedName, expirationUnixTime int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, expirationUnixTime)
+}
+
+func (c *MemoryCache) Reserve(key string, value *types.NamespacedName, expirationUnixTime int64) (*types.NamespacedName, bool, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.fqdnMap[key] = &element{
-		Value:     value,
-		ExpiresAt: expirationUnixTime,
+	if element, found := c.fqdnMap[key]; found {
+		return element.Value, false, nil
 	}
+
+	c.set(key, value, expirationUnixTime)
+
+	return value, true, nil
+}
+
+func (c *MemoryCache) Quarantine(key string, owner types.NamespacedName, expirationUnixTime int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setElement(&element{Value: &owner, ExpiresAt: expirationUnixTime, Quarantined: true, key: key})
+}
+
+// set sets the entry along with its reverse indexes, the caller must hold the lock.
+func (c *MemoryCache) set(key string, value *types.NamespacedName, expirationUnixTime int64) {
+	c.setElement(&element{Value: value, ExpiresAt: expirationUnixTime, key: key})
 }
 
-func (c *Cache) Get(key string) (*types.NamespacedName, bool) {
+// setElement sets the element as modified now, the caller must hold the lock.
+func (c *MemoryCache) setElement(newElement *element) {
+	key := newElement.key
+
+	// An overwritten entry is unindexed without publishing a DELETE event.
+	if element, found := c.fqdnMap[key]; found {
+		c.unindex(key, element)
+		c.unschedule(element)
+	}
+
+	newElement.ModifiedAt = time.Now().Unix()
+
+	c.fqdnMap[key] = newElement
+	c.schedule(newElement)
+
+	c.publish(Event{Type: EventSet, Entry: newElement.entry()})
+
+	c.index(key, newElement)
+}
+
+// index adds the entry to the reverse indexes, the caller must hold the lock.
+func (c *MemoryCache) index(key string, element *element) {
+	value := element.Value
+	if value == nil {
+		return
+	}
+
+	if _, found := c.ownerIndex[*value]; !found {
+		c.ownerIndex[*value] = make(map[string]struct{})
+	}
+
+	c.ownerIndex[*value][key] = struct{}{}
+
+	if _, found := c.namespaceIndex[value.Namespace]; !found {
+		c.namespaceIndex[value.Namespace] = make(map[string]struct{})
+	}
+
+	c.namespaceIndex[value.Namespace][key] = struct{}{}
+}
+
+func (c *MemoryCache) Get(key string) (*types.NamespacedName, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -72,14 +170,205 @@ func (c *Cache) Get(key string) (*types.NamespacedName, bool) {
 	return element.Value, true
 }
 
-func (c *Cache) Delete(key string) {
+func (c *MemoryCache) GetEntry(key string) (*Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	element, found := c.fqdnMap[key]
+	if !found {
+		return nil, false
+	}
+
+	entry := element.entry()
+
+	return &entry, true
+}
+
+func (c *MemoryCache) LookupEntry(key string) (*Entry, error) {
+	entry, _ := c.GetEntry(key)
+
+	return entry, nil
+}
+
+func (c *MemoryCache) AddClaimant(key string, claimant types.NamespacedName) ([]types.NamespacedName, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.fqdnMap[key]
+	if !found {
+		return nil, false
+	}
+
+	if !slices.Contains(element.entry().AllClaimants(), claimant) {
+		element.Claimants = append(element.Claimants, claimant)
+		element.ModifiedAt = time.Now().Unix()
+
+		c.publish(Event{Type: EventSet, Entry: element.entry()})
+	}
+
+	return element.entry().AllClaimants(), true
+}
+
+func (c *MemoryCache) Release(key string, claimant types.NamespacedName) []types.NamespacedName {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	element, found := c.fqdnMap[key]
+	if !found {
+		return nil
+	}
+
+	switch {
+	case element.Value != nil && *element.Value == claimant && len(element.Claimants) == 0:
+		c.delete(key)
+
+		return nil
+	case element.Value != nil && *element.Value == claimant:
+		// The next claimant becomes the owner.
+		owner := element.Claimants[0]
+
+		c.unindex(key, element)
+		element.Value = &owner
+		element.Claimants = slices.Clone(element.Claimants[1:])
+		c.index(key, element)
+	case slices.Contains(element.Claimants, claimant):
+		element.Claimants = slices.DeleteFunc(slices.Clone(element.Claimants), func(nn types.NamespacedName) bool {
+			return nn == claimant
+		})
+	default:
+		return element.entry().AllClaimants()
+	}
+
+	element.ModifiedAt = time.Now().Unix()
+
+	c.publish(Event{Type: EventSet, Entry: element.entry()})
+
+	return element.entry().AllClaimants()
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.delete(key)
+}
+
+// delete deletes the entry along with its reverse indexes, the caller must hold the lock.
+func (c *MemoryCache) delete(key string) {
+	element, found := c.fqdnMap[key]
+	if !found {
+		return
+	}
+
 	delete(c.fqdnMap, key)
+	c.unschedule(element)
+
+	c.publish(Event{Type: EventDelete, Entry: Entry{Key: key}})
+
+	c.unindex(key, element)
+}
+
+// unindex removes the entry from the reverse indexes, the caller must hold the lock.
+func (c *MemoryCache) unindex(key string, element *element) {
+	if element.Value == nil {
+		return
+	}
+
+	delete(c.ownerIndex[*element.Value], key)
+
+	if len(c.ownerIndex[*element.Value]) == 0 {
+		delete(c.ownerIndex, *element.Value)
+	}
+
+	delete(c.namespaceIndex[element.Value.Namespace], key)
+
+	if len(c.namespaceIndex[element.Value.Namespace]) == 0 {
+		delete(c.namespaceIndex, element.Value.Namespace)
+	}
+}
+
+// Entries returns a copy of all the entries sorted by key.
+func (c *MemoryCache) Entries() []Entry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(c.fqdnMap))
+
+	for _, element := range c.fqdnMap {
+		entries = append(entries, element.entry())
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return entries
+}
+
+// GetKeysByOwner returns the keys owned by the object.
+func (c *MemoryCache) GetKeysByOwner(owner types.NamespacedName) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return keysOf(c.ownerIndex[owner])
+}
+
+// GetKeysByNamespace returns the keys owned by the objects of the namespace.
+func (c *MemoryCache) GetKeysByNamespace(namespace string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return keysOf(c.namespaceIndex[namespace])
+}
+
+// DeleteByOwner deletes all the entries owned by the object and returns the number of deleted entries.
+func (c *MemoryCache) DeleteByOwner(owner types.NamespacedName) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := keysOf(c.ownerIndex[owner])
+
+	for _, key := range keys {
+		c.delete(key)
+	}
+
+	return len(keys)
 }
 
-func (c *Cache) KeyExists(key string) bool {
+// DeleteByNamespace deletes all the entries owned by the objects of the namespace and returns the number of
+// deleted entries.
+func (c *MemoryCache) DeleteByNamespace(namespace string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := keysOf(c.namespaceIndex[namespace])
+
+	for _, key := range keys {
+		c.delete(key)
+	}
+
+	return len(keys)
+}
+
+// DeleteByPrefix deletes all the entries whose key starts with the prefix and returns the number of deleted entries.
+func (c *MemoryCache) DeleteByPrefix(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deleted := 0
+
+	for key := range c.fqdnMap {
+		if strings.HasPrefix(key, prefix) {
+			c.delete(key)
+
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+func (c *MemoryCache) KeyExists(key string) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -88,7 +377,7 @@ func (c *Cache) KeyExists(key string) bool {
 	return found
 }
 
-func (c *Cache) IsKeyPersisted(key string) *bool {
+func (c *MemoryCache) IsKeyPersisted(key string) *bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -100,7 +389,7 @@ func (c *Cache) IsKeyPersisted(key string) *bool {
 	return utils.BoolPointer(entry.ExpiresAt == 0)
 }
 
-func (c *Cache) StartCleaner() {
+func (c *MemoryCache) StartCleaner() {
 	go func() {
 	out:
 		for {
@@ -114,17 +403,66 @@ func (c *Cache) StartCleaner() {
 	}()
 }
 
-func (c *Cache) cleanUp() {
+// cleanUp deletes the expired entries in batches, releasing the lock between the batches.
+func (c *MemoryCache) cleanUp() {
 	now := time.Now().Unix()
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	for key, element := range c.fqdnMap {
-		if element.ExpiresAt > 0 && now >= element.ExpiresAt {
-			delete(c.fqdnMap, key)
+	for {
+		keys := c.cleanUpBatch(now)
 
+		// The keys are logged once the lock is released.
+		for _, key := range keys {
 			logger.Info("cache entry is expired hence deleted", "entry", key)
 		}
+
+		if len(keys) < cleanUpBatchSize {
+			return
+		}
 	}
 }
+
+func (c *MemoryCache) Watch(ctx context.Context) <-chan Event {
+	events := make(chan Event, watchBufferSize)
+
+	c.watchersMu.Lock()
+	c.watchers[events] = struct{}{}
+	c.watchersMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		c.watchersMu.Lock()
+		delete(c.watchers, events)
+		close(events)
+		c.watchersMu.Unlock()
+	}()
+
+	return events
+}
+
+// publish sends the event to the watchers without blocking.
+func (c *MemoryCache) publish(event Event) {
+	c.watchersMu.RLock()
+	defer c.watchersMu.RUnlock()
+
+	for events := range c.watchers {
+		select {
+		case events <- event:
+		default:
+			logger.Info("cache watcher is too slow; event dropped", "entry", event.Entry.Key)
+		}
+	}
+}
+
+// keysOf returns the sorted keys of the set.
+func keysOf(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+
+	for key := range set {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCacheOwnerIndex(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer func() { cache.CleanUpStopChan <- true }()
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+	other := types.NamespacedName{Namespace: "test", Name: "other"}
+
+	cache.Set("private/a.example.com", &echo, 0)
+	cache.Set("public/a.example.com", &echo, 0)
+	cache.Set("private/b.example.com", &other, 0)
+
+	assert.Equal(t, []string{"private/a.example.com", "public/a.example.com"}, cache.GetKeysByOwner(echo))
+	assert.Len(t, cache.GetKeysByNamespace("test"), 3)
+
+	// Overwriting an entry moves it to the new owner.
+	cache.Set("public/a.example.com", &other, 0)
+
+	assert.Equal(t, []string{"private/a.example.com"}, cache.GetKeysByOwner(echo))
+	assert.Equal(t, []string{"private/b.example.com", "public/a.example.com"}, cache.GetKeysByOwner(other))
+
+	cache.Delete("private/a.example.com")
+
+	assert.Empty(t, cache.GetKeysByOwner(echo))
+
+	assert.Equal(t, 1, cache.DeleteByPrefix("public/"))
+	assert.Equal(t, []string{"private/b.example.com"}, cache.GetKeysByNamespace("test"))
+
+	assert.Equal(t, 1, cache.DeleteByNamespace("test"))
+	assert.False(t, cache.KeyExists("private/b.example.com"))
+	assert.Empty(t, cache.GetKeysByNamespace("test"))
+}
+
+func TestCacheDeleteByOwner(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer func() { cache.CleanUpStopChan <- true }()
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+
+	cache.Set("private/a.example.com", &echo, 0)
+	cache.Set("private/b.example.com", &echo, time.Now().Add(-time.Second).Unix())
+
+	// Expired entries are removed from the indexes as well.
+	cache.cleanUp()
+
+	assert.Equal(t, []string{"private/a.example.com"}, cache.GetKeysByOwner(echo))
+	assert.Equal(t, 1, cache.DeleteByOwner(echo))
+	assert.Empty(t, cache.GetKeysByNamespace("test"))
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot", "cache.json")
+
+	cache := NewCache(time.Minute)
+	defer func() { cache.CleanUpStopChan <- true }()
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+	other := types.NamespacedName{Namespace: "test", Name: "other"}
+	expiresAt := time.Now().Add(time.Minute).Unix()
+
+	cache.Set("private/a.example.com", &echo, 0)
+	cache.Set("private/b.example.com", &echo, expiresAt)
+	cache.Set("private/c.example.com", &echo, time.Now().Add(-time.Second).Unix())
+	cache.AddClaimant("private/a.example.com", other)
+	cache.Quarantine("private/d.example.com", other, expiresAt)
+
+	assert.Nil(t, SaveSnapshot(cache, path))
+
+	restored := NewCache(time.Minute)
+	defer func() { restored.CleanUpStopChan <- true }()
+
+	loaded, err := LoadSnapshot(restored, path)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, loaded)
+	assert.Equal(t, []Entry{
+		{Key: "private/a.example.com", Owner: echo, Claimants: []types.NamespacedName{other}},
+		{Key: "private/b.example.com", Owner: echo, ExpiresAt: expiresAt},
+		{Key: "private/d.example.com", Owner: other, ExpiresAt: expiresAt, Quarantined: true},
+	}, withoutModifiedAt(t, restored.Entries()...))
+	assert.Equal(t, []string{"private/a.example.com", "private/b.example.com"}, restored.GetKeysByOwner(echo))
+
+	loaded, err = LoadSnapshot(restored, filepath.Join(t.TempDir(), "missing.json"))
+
+	assert.Nil(t, err)
+	assert.Zero(t, loaded)
+}
@@ -0,0 +1,91 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"container/heap"
+)
+
+// cleanUpBatchSize is the maximum number of entries expired while holding the lock, so a burst of expiries does not
+// stall the admissions.
+const cleanUpBatchSize = 1000
+
+// expiryHeap is a min-heap of the expiring elements ordered by their expiry, the persisted elements are not pushed.
+type expiryHeap []*element
+
+var _ heap.Interface = &expiryHeap{}
+
+func (h expiryHeap) Len() int {
+	return len(h)
+}
+
+func (h expiryHeap) Less(i, j int) bool {
+	return h[i].ExpiresAt < h[j].ExpiresAt
+}
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	element, _ := x.(*element)
+	element.heapIndex = len(*h)
+	*h = append(*h, element)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	element := old[len(old)-1]
+	old[len(old)-1] = nil
+	element.heapIndex = -1
+	*h = old[:len(old)-1]
+
+	return element
+}
+
+// schedule pushes the element to the expiry heap unless it's persisted, the caller must hold the lock.
+func (c *MemoryCache) schedule(element *element) {
+	element.heapIndex = -1
+
+	if element.ExpiresAt > 0 {
+		heap.Push(&c.expiries, element)
+	}
+}
+
+// unschedule removes the element from the expiry heap, the caller must hold the lock.
+func (c *MemoryCache) unschedule(element *element) {
+	if element.heapIndex >= 0 {
+		heap.Remove(&c.expiries, element.heapIndex)
+	}
+}
+
+// cleanUpBatch deletes up to cleanUpBatchSize entries expired at the given time and returns their keys. Only the due
+// entries are touched.
+func (c *MemoryCache) cleanUpBatch(now int64) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0)
+
+	for len(keys) < cleanUpBatchSize && len(c.expiries) > 0 && c.expiries[0].ExpiresAt <= now {
+		key := c.expiries[0].key
+
+		c.delete(key)
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const benchmarkEntries = 100000
+
+func TestCacheExpiry(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer func() { cache.CleanUpStopChan <- true }()
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+	now := time.Now().Unix()
+
+	cache.Set("private/a.example.com", &echo, now-2)
+	cache.Set("private/b.example.com", &echo, now+60)
+	cache.Set("private/c.example.com", &echo, now-1)
+	cache.Set("private/d.example.com", &echo, 0)
+
+	// Overwriting an expiring entry reschedules it.
+	cache.Set("private/c.example.com", &echo, 0)
+	cache.Set("private/b.example.com", &echo, now-1)
+
+	assert.Len(t, cache.expiries, 2)
+
+	cache.cleanUp()
+
+	assert.Equal(t, []string{"private/c.example.com", "private/d.example.com"}, cache.GetKeysByOwner(echo))
+	assert.Empty(t, cache.expiries)
+
+	// Deleted entries are unscheduled.
+	cache.Set("private/e.example.com", &echo, now+60)
+	cache.Delete("private/e.example.com")
+
+	assert.Empty(t, cache.expiries)
+}
+
+func TestCacheCleanUpBatches(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer func() { cache.CleanUpStopChan <- true }()
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+	expiresAt := time.Now().Add(-time.Second).Unix()
+
+	for i := 0; i < cleanUpBatchSize+1; i++ {
+		cache.Set(fmt.Sprintf("private/%d.example.com", i), &echo, expiresAt)
+	}
+
+	assert.Len(t, cache.cleanUpBatch(time.Now().Unix()), cleanUpBatchSize)
+
+	cache.cleanUp()
+
+	assert.Empty(t, cache.Entries())
+}
+
+// newBenchmarkCache returns a cache of benchmarkEntries entries, half of them persisted and the others expiring at
+// the given time.
+func newBenchmarkCache(expiresAt int64) *MemoryCache {
+	cache := NewCache(time.Hour)
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+
+	for i := 0; i < benchmarkEntries; i++ {
+		var entryExpiresAt int64
+
+		if i%2 == 0 {
+			entryExpiresAt = expiresAt
+		}
+
+		cache.Set(fmt.Sprintf("private/%d.example.com", i), &echo, entryExpiresAt)
+	}
+
+	return cache
+}
+
+// benchmarkCleanUp runs cleanUp on a fresh cache per iteration and reports the longest time the lock is held by a
+// single batch.
+func benchmarkCleanUp(b *testing.B, expiresAt int64) {
+	b.Helper()
+
+	var maxLockHold time.Duration
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		cache := newBenchmarkCache(expiresAt)
+		now := time.Now().Unix()
+		b.StartTimer()
+
+		// The same as cleanUp, as each batch holds the lock for its whole duration.
+		for {
+			start := time.Now()
+			keys := cache.cleanUpBatch(now)
+			maxLockHold = max(maxLockHold, time.Since(start))
+
+			if len(keys) < cleanUpBatchSize {
+				break
+			}
+		}
+
+		b.StopTimer()
+		cache.CleanUpStopChan <- true
+		b.StartTimer()
+	}
+
+	b.ReportMetric(float64(maxLockHold.Nanoseconds()), "max-lock-hold-ns")
+}
+
+// BenchmarkCleanUpNoneDue measures a tick with no due entry, which used to scan the whole map.
+func BenchmarkCleanUpNoneDue(b *testing.B) {
+	benchmarkCleanUp(b, time.Now().Add(time.Hour).Unix())
+}
+
+// BenchmarkCleanUpAllDue measures a tick expiring half of the entries at once.
+func BenchmarkCleanUpAllDue(b *testing.B) {
+	benchmarkCleanUp(b, time.Now().Add(-time.Second).Unix())
+}
+
+// BenchmarkReserveDuringCleanUp measures the admissions' reservations while the entries are expiring.
+func BenchmarkReserveDuringCleanUp(b *testing.B) {
+	cache := newBenchmarkCache(time.Now().Add(-time.Second).Unix())
+	defer func() { cache.CleanUpStopChan <- true }()
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+	expiresAt := time.Now().Add(time.Minute).Unix()
+
+	go cache.cleanUp()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _, _ = cache.Reserve(fmt.Sprintf("public/%d.example.com", i), &echo, expiresAt)
+	}
+}
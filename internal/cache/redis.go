@@ -0,0 +1,658 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	redisEntryPrefix     = "entry:"
+	redisOwnerPrefix     = "owner:"
+	redisNamespacePrefix = "namespace:"
+	redisEventsChannel   = "events"
+	redisScanCount       = "1000"
+)
+
+// RedisCache is the Cache implementation backed by a Redis-protocol server, so multiple replicas share the
+// reservations. Each entry is stored as a JSON string expiring along with the reservation, and the reverse indexes
+// are stored as sets. Since the expired entries are removed by the server, their members are pruned from the sets
+// lazily and no DELETE event is published for them.
+type RedisCache struct {
+	client    *respClient
+	keyPrefix string
+}
+
+var _ Cache = &RedisCache{}
+
+// NewRedisCache returns a RedisCache after checking the server is reachable.
+func NewRedisCache(cfg config.Redis) (*RedisCache, error) {
+	timeout := time.Duration(cfg.DialTimeoutSecond) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	cache := &RedisCache{
+		client: &respClient{
+			address:  cfg.Address,
+			password: cfg.Password,
+			db:       cfg.DB,
+			timeout:  timeout,
+		},
+		keyPrefix: cfg.KeyPrefix,
+	}
+
+	if _, err := cache.client.do("PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to the redis server: %w", err)
+	}
+
+	return cache, nil
+}
+
+func (c *RedisCache) Set(key string, value *types.NamespacedName, expirationUnixTime int64) {
+	entry := Entry{Key: key, ExpiresAt: expirationUnixTime}
+
+	if value != nil {
+		entry.Owner = *value
+	}
+
+	err := c.update(key, func(*Entry) (*Entry, bool, error) {
+		return &entry, true, nil
+	})
+	if err != nil {
+		logger.Error(err, "failed to set the cache entry", "entry", key)
+	}
+}
+
+func (c *RedisCache) Reserve(key string, value *types.NamespacedName, expirationUnixTime int64) (*types.NamespacedName, bool, error) {
+	entry := Entry{Key: key, ExpiresAt: expirationUnixTime}
+
+	if value != nil {
+		entry.Owner = *value
+	}
+
+	var owner *types.NamespacedName
+
+	err := c.update(key, func(current *Entry) (*Entry, bool, error) {
+		if current != nil {
+			owner = &current.Owner
+
+			return nil, false, nil
+		}
+
+		owner = nil
+
+		return &entry, true, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if owner != nil {
+		return owner, false, nil
+	}
+
+	return value, true, nil
+}
+
+func (c *RedisCache) Get(key string) (*types.NamespacedName, bool) {
+	entry, err := c.get(key)
+	if err != nil {
+		logger.Error(err, "failed to get the cache entry", "entry", key)
+	}
+
+	if entry == nil {
+		return nil, false
+	}
+
+	return &entry.Owner, true
+}
+
+func (c *RedisCache) GetEntry(key string) (*Entry, bool) {
+	entry, err := c.get(key)
+	if err != nil {
+		logger.Error(err, "failed to get the cache entry", "entry", key)
+	}
+
+	return entry, entry != nil
+}
+
+func (c *RedisCache) LookupEntry(key string) (*Entry, error) {
+	return c.get(key)
+}
+
+func (c *RedisCache) Quarantine(key string, owner types.NamespacedName, expirationUnixTime int64) {
+	err := c.update(key, func(*Entry) (*Entry, bool, error) {
+		return &Entry{Key: key, Owner: owner, ExpiresAt: expirationUnixTime, Quarantined: true}, true, nil
+	})
+	if err != nil {
+		logger.Error(err, "failed to set the cache entry", "entry", key)
+	}
+}
+
+func (c *RedisCache) AddClaimant(key string, claimant types.NamespacedName) ([]types.NamespacedName, bool) {
+	var claimants []types.NamespacedName
+
+	err := c.update(key, func(current *Entry) (*Entry, bool, error) {
+		if current == nil {
+			claimants = nil
+
+			return nil, false, nil
+		}
+
+		claimants = current.AllClaimants()
+		if slices.Contains(claimants, claimant) {
+			return nil, false, nil
+		}
+
+		current.Claimants = append(current.Claimants, claimant)
+		claimants = current.AllClaimants()
+
+		return current, true, nil
+	})
+	if err != nil {
+		logger.Error(err, "failed to set the cache entry", "entry", key)
+
+		return nil, false
+	}
+
+	return claimants, claimants != nil
+}
+
+func (c *RedisCache) Release(key string, claimant types.NamespacedName) []types.NamespacedName {
+	var claimants []types.NamespacedName
+
+	err := c.update(key, func(current *Entry) (*Entry, bool, error) {
+		claimants = nil
+
+		if current == nil {
+			return nil, false, nil
+		}
+
+		switch {
+		case current.Owner == claimant && len(current.Claimants) == 0:
+			return nil, true, nil
+		case current.Owner == claimant:
+			// The next claimant becomes the owner.
+			current.Owner = current.Claimants[0]
+			current.Claimants = current.Claimants[1:]
+		case slices.Contains(current.Claimants, claimant):
+			current.Claimants = slices.DeleteFunc(current.Claimants, func(nn types.NamespacedName) bool {
+				return nn == claimant
+			})
+		default:
+			claimants = current.AllClaimants()
+
+			return nil, false, nil
+		}
+
+		claimants = current.AllClaimants()
+
+		return current, true, nil
+	})
+	if err != nil {
+		logger.Error(err, "failed to set the cache entry", "entry", key)
+	}
+
+	return claimants
+}
+
+func (c *RedisCache) Delete(key string) {
+	c.delete(key, nil)
+}
+
+func (c *RedisCache) DeleteByPrefix(prefix string) int {
+	deleted := 0
+
+	for _, entry := range c.scan(prefix) {
+		if c.delete(entry.Key, nil) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+func (c *RedisCache) KeyExists(key string) bool {
+	reply, err := c.client.do("EXISTS", c.keyPrefix+redisEntryPrefix+key)
+	if err != nil {
+		logger.Error(err, "failed to check the cache entry", "entry", key)
+
+		return false
+	}
+
+	count, _ := reply.(int64)
+
+	return count > 0
+}
+
+func (c *RedisCache) IsKeyPersisted(key string) *bool {
+	entry, err := c.get(key)
+	if err != nil {
+		logger.Error(err, "failed to get the cache entry", "entry", key)
+	}
+
+	if entry == nil {
+		return nil
+	}
+
+	persisted := entry.ExpiresAt == 0
+
+	return &persisted
+}
+
+func (c *RedisCache) Entries() []Entry {
+	return c.scan("")
+}
+
+func (c *RedisCache) GetKeysByOwner(owner types.NamespacedName) []string {
+	return c.getIndexedKeys(c.keyPrefix+redisOwnerPrefix+owner.String(), func(entry Entry) bool {
+		return entry.Owner == owner
+	})
+}
+
+func (c *RedisCache) GetKeysByNamespace(namespace string) []string {
+	return c.getIndexedKeys(c.keyPrefix+redisNamespacePrefix+namespace, func(entry Entry) bool {
+		return entry.Owner.Namespace == namespace
+	})
+}
+
+func (c *RedisCache) DeleteByOwner(owner types.NamespacedName) int {
+	deleted := 0
+
+	for _, key := range c.GetKeysByOwner(owner) {
+		if c.delete(key, func(entry Entry) bool { return entry.Owner == owner }) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+func (c *RedisCache) DeleteByNamespace(namespace string) int {
+	deleted := 0
+
+	for _, key := range c.GetKeysByNamespace(namespace) {
+		if c.delete(key, func(entry Entry) bool { return entry.Owner.Namespace == namespace }) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// Watch subscribes to the events channel on a dedicated connection, which is re-established on errors.
+func (c *RedisCache) Watch(ctx context.Context) <-chan Event {
+	events := make(chan Event, watchBufferSize)
+
+	conn, err := c.subscribe()
+	if err != nil {
+		logger.Error(err, "failed to subscribe to the cache events")
+	}
+
+	go func() {
+		defer close(events)
+
+		// The connection is closed once the context is done to unblock the read.
+		var mu sync.Mutex
+
+		stop := context.AfterFunc(ctx, func() {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if conn != nil {
+				conn.close()
+			}
+		})
+		defer stop()
+
+		for ctx.Err() == nil {
+			if conn == nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(c.client.timeout):
+				}
+
+				subscribed, err := c.subscribe()
+				if err != nil {
+					logger.Error(err, "failed to subscribe to the cache events")
+
+					continue
+				}
+
+				mu.Lock()
+				conn = subscribed
+				mu.Unlock()
+
+				if ctx.Err() != nil {
+					subscribed.close()
+
+					return
+				}
+			}
+
+			reply, err := conn.read()
+			if err != nil {
+				mu.Lock()
+				conn.close()
+				conn = nil
+				mu.Unlock()
+
+				continue
+			}
+
+			c.dispatch(events, reply)
+		}
+	}()
+
+	return events
+}
+
+// dispatch sends the event of a pub/sub message to the watcher without blocking.
+func (c *RedisCache) dispatch(events chan<- Event, reply interface{}) {
+	message, ok := reply.([]interface{})
+	if !ok || len(message) != 3 || message[0] != "message" {
+		return
+	}
+
+	event := Event{}
+
+	if payload, _ := message[2].(string); json.Unmarshal([]byte(payload), &event) != nil {
+		return
+	}
+
+	select {
+	case events <- event:
+	default:
+		logger.Info("cache watcher is too slow; event dropped", "entry", event.Entry.Key)
+	}
+}
+
+func (c *RedisCache) subscribe() (*respConn, error) {
+	conn, err := dialResp(c.client.address, c.client.password, c.client.db, c.client.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// The subscription is confirmed before returning, so no event published afterwards is missed.
+	if _, err := conn.do("SUBSCRIBE", c.keyPrefix+redisEventsChannel); err != nil {
+		conn.close()
+
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// redisTransactionAttempts bounds the retries of a transaction aborted by the concurrent writes of other replicas.
+const redisTransactionAttempts = 16
+
+// errExpired is returned by update if the entry to store is already expired.
+var errExpired = errors.New("cache entry is expired")
+
+// update atomically replaces the entry by the one mutate returns given the current entry, or deletes it if mutate
+// returns nil. Nothing is written if mutate returns false. The entry, its indexes and the event are written in a
+// single transaction, which is retried if the entry is modified concurrently, so mutate might be called again.
+func (c *RedisCache) update(key string, mutate func(current *Entry) (*Entry, bool, error)) error {
+	entryKey := c.keyPrefix + redisEntryPrefix + key
+
+	for attempt := 0; attempt < redisTransactionAttempts; attempt++ {
+		_, err := c.client.transaction([]string{entryKey}, func(conn *respConn) ([][]string, error) {
+			reply, err := conn.do("GET", entryKey)
+			if err != nil {
+				return nil, err
+			}
+
+			var current, mutable *Entry
+
+			if reply != nil {
+				if current, err = decodeEntry(reply); err != nil {
+					return nil, err
+				}
+
+				// mutate gets a copy, as the owner of the current entry is unindexed.
+				copied := *current
+				copied.Claimants = slices.Clone(current.Claimants)
+				mutable = &copied
+			}
+
+			next, write, err := mutate(mutable)
+			if err != nil || !write {
+				return nil, err
+			}
+
+			return c.updateCommands(key, current, next)
+		})
+		if !errors.Is(err, errTransactionAborted) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to update the cache entry %s: %w", key, errTransactionAborted)
+}
+
+// updateCommands returns the commands replacing the current entry by the next one as modified now, or deleting it
+// if next is nil, along with their indexes and event.
+func (c *RedisCache) updateCommands(key string, current, next *Entry) ([][]string, error) {
+	entryKey := c.keyPrefix + redisEntryPrefix + key
+	commands := make([][]string, 0, 6)
+	event := Event{Type: EventDelete, Entry: Entry{Key: key}}
+
+	if current != nil && (next == nil || current.Owner != next.Owner) {
+		commands = append(commands,
+			[]string{"SREM", c.keyPrefix + redisOwnerPrefix + current.Owner.String(), key},
+			[]string{"SREM", c.keyPrefix + redisNamespacePrefix + current.Owner.Namespace, key},
+		)
+	}
+
+	if next == nil {
+		if current == nil {
+			return nil, nil
+		}
+
+		commands = append(commands, []string{"DEL", entryKey})
+	} else {
+		next.ModifiedAt = time.Now().Unix()
+
+		data, err := json.Marshal(next)
+		if err != nil {
+			return nil, err
+		}
+
+		set := []string{"SET", entryKey, string(data)}
+
+		if next.ExpiresAt > 0 {
+			ttl := time.Until(time.Unix(next.ExpiresAt, 0)).Milliseconds()
+			if ttl <= 0 {
+				return nil, errExpired
+			}
+
+			set = append(set, "PX", strconv.FormatInt(ttl, 10))
+		}
+
+		commands = append(commands, set,
+			[]string{"SADD", c.keyPrefix + redisOwnerPrefix + next.Owner.String(), key},
+			[]string{"SADD", c.keyPrefix + redisNamespacePrefix + next.Owner.Namespace, key},
+		)
+		event = Event{Type: EventSet, Entry: *next}
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(commands, []string{"PUBLISH", c.keyPrefix + redisEventsChannel, string(data)}), nil
+}
+
+func (c *RedisCache) get(key string) (*Entry, error) {
+	reply, err := c.client.do("GET", c.keyPrefix+redisEntryPrefix+key)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+
+	return decodeEntry(reply)
+}
+
+// delete deletes the entry if it matches, or if matches is nil. It returns false if the entry was not deleted.
+func (c *RedisCache) delete(key string, matches func(Entry) bool) bool {
+	deleted := false
+
+	err := c.update(key, func(current *Entry) (*Entry, bool, error) {
+		deleted = current != nil && (matches == nil || matches(*current))
+
+		return nil, deleted, nil
+	})
+	if err != nil {
+		logger.Error(err, "failed to delete the cache entry", "entry", key)
+
+		return false
+	}
+
+	return deleted
+}
+
+// getIndexedKeys returns the sorted members of the index set matching their entries, the others are pruned unless
+// their entries are modified meanwhile.
+func (c *RedisCache) getIndexedKeys(index string, matches func(Entry) bool) []string {
+	reply, err := c.client.do("SMEMBERS", index)
+	if err != nil {
+		logger.Error(err, "failed to get the cache index", "index", index)
+
+		return []string{}
+	}
+
+	members, _ := reply.([]interface{})
+	keys := make([]string, 0, len(members))
+
+	for _, member := range members {
+		key, _ := member.(string)
+		entryKey := c.keyPrefix + redisEntryPrefix + key
+		matched := false
+
+		_, err := c.client.transaction([]string{entryKey}, func(conn *respConn) ([][]string, error) {
+			reply, err := conn.do("GET", entryKey)
+			if err != nil {
+				return nil, err
+			}
+
+			if reply != nil {
+				entry, err := decodeEntry(reply)
+				if err != nil {
+					return nil, err
+				}
+
+				if matched = matches(*entry); matched {
+					return nil, nil
+				}
+			}
+
+			return [][]string{{"SREM", index, key}}, nil
+		})
+		if err != nil && !errors.Is(err, errTransactionAborted) {
+			logger.Error(err, "failed to prune the cache index", "index", index)
+		}
+
+		if err == nil && matched {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+// scan returns the entries whose key starts with the prefix sorted by key.
+func (c *RedisCache) scan(prefix string) []Entry {
+	entries := make([]Entry, 0)
+	pattern := c.keyPrefix + redisEntryPrefix + escapeGlob(prefix) + "*"
+	cursor := "0"
+
+	for {
+		reply, err := c.client.do("SCAN", cursor, "MATCH", pattern, "COUNT", redisScanCount)
+		if err != nil {
+			logger.Error(err, "failed to scan the cache entries")
+
+			break
+		}
+
+		page, _ := reply.([]interface{})
+		if len(page) != 2 {
+			break
+		}
+
+		cursor, _ = page[0].(string)
+		keys, _ := page[1].([]interface{})
+
+		for _, key := range keys {
+			redisKey, _ := key.(string)
+
+			if entry, err := c.get(strings.TrimPrefix(redisKey, c.keyPrefix+redisEntryPrefix)); err == nil && entry != nil {
+				entries = append(entries, *entry)
+			}
+		}
+
+		if cursor == "0" || cursor == "" {
+			break
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return entries
+}
+
+func decodeEntry(reply interface{}) (*Entry, error) {
+	data, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected cache entry type %T", reply)
+	}
+
+	entry := &Entry{}
+
+	if err := json.Unmarshal([]byte(data), entry); err != nil {
+		return nil, fmt.Errorf("failed to decode the cache entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// escapeGlob escapes the special characters of the glob-style patterns matched by SCAN.
+func escapeGlob(s string) string {
+	var builder strings.Builder
+
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			builder.WriteRune('\\')
+		}
+
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,408 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedisServer is an in-process stand-in of a Redis server, implementing the subset of the commands used by
+// RedisCache. The keys expire lazily, and each modification of a key bumps its version checked by the transactions.
+type fakeRedisServer struct {
+	listener    net.Listener
+	password    string
+	mu          sync.Mutex
+	strings     map[string]fakeRedisValue
+	sets        map[string]map[string]struct{}
+	versions    map[string]uint64
+	subscribers map[string]map[*fakeRedisConn]struct{}
+}
+
+type fakeRedisValue struct {
+	data      string
+	expiresAt time.Time // zero if persisted
+}
+
+type fakeRedisConn struct {
+	conn          net.Conn
+	writeMu       sync.Mutex
+	authenticated bool
+	watched       map[string]uint64
+	multi         bool
+	queued        [][]string
+}
+
+func newFakeRedisServer(t *testing.T, password string) *fakeRedisServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := &fakeRedisServer{
+		listener:    listener,
+		password:    password,
+		strings:     make(map[string]fakeRedisValue),
+		sets:        make(map[string]map[string]struct{}),
+		versions:    make(map[string]uint64),
+		subscribers: make(map[string]map[*fakeRedisConn]struct{}),
+	}
+
+	go server.serve()
+
+	t.Cleanup(func() { listener.Close() })
+
+	return server
+}
+
+func (s *fakeRedisServer) address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(&fakeRedisConn{conn: conn, authenticated: s.password == ""})
+	}
+}
+
+func (s *fakeRedisServer) handle(fc *fakeRedisConn) {
+	defer func() {
+		s.mu.Lock()
+		for _, subscribers := range s.subscribers {
+			delete(subscribers, fc)
+		}
+		s.mu.Unlock()
+
+		fc.conn.Close()
+	}()
+
+	// The client parser is reused, as the commands are sent as arrays of bulk strings.
+	reader := &respConn{conn: fc.conn, reader: bufio.NewReader(fc.conn)}
+
+	for {
+		request, err := reader.read()
+		if err != nil {
+			return
+		}
+
+		elements, _ := request.([]interface{})
+		args := make([]string, 0, len(elements))
+
+		for _, element := range elements {
+			arg, _ := element.(string)
+			args = append(args, arg)
+		}
+
+		if len(args) == 0 {
+			fc.write("-ERR empty command\r\n")
+
+			continue
+		}
+
+		fc.write(s.execute(fc, strings.ToUpper(args[0]), args[1:]))
+	}
+}
+
+func (fc *fakeRedisConn) write(reply string) {
+	fc.writeMu.Lock()
+	defer fc.writeMu.Unlock()
+
+	_, _ = fc.conn.Write([]byte(reply))
+}
+
+func (s *fakeRedisServer) execute(fc *fakeRedisConn, command string, args []string) string {
+	if command == "AUTH" {
+		if len(args) != 1 || args[0] != s.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+
+		fc.authenticated = true
+
+		return "+OK\r\n"
+	}
+
+	if !fc.authenticated {
+		return "-NOAUTH Authentication required.\r\n"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if fc.multi && command != "EXEC" && command != "DISCARD" {
+		fc.queued = append(fc.queued, append([]string{command}, args...))
+
+		return "+QUEUED\r\n"
+	}
+
+	switch command {
+	case "WATCH":
+		if fc.watched == nil {
+			fc.watched = make(map[string]uint64)
+		}
+
+		for _, key := range args {
+			s.get(key)
+			fc.watched[key] = s.versions[key]
+		}
+
+		return "+OK\r\n"
+	case "UNWATCH":
+		fc.watched = nil
+
+		return "+OK\r\n"
+	case "MULTI":
+		fc.multi = true
+
+		return "+OK\r\n"
+	case "DISCARD", "EXEC":
+		if !fc.multi {
+			return fmt.Sprintf("-ERR %s without MULTI\r\n", command)
+		}
+
+		watched, queued := fc.watched, fc.queued
+		fc.watched, fc.multi, fc.queued = nil, false, nil
+
+		if command == "DISCARD" {
+			return "+OK\r\n"
+		}
+
+		for key, version := range watched {
+			if s.get(key); s.versions[key] != version {
+				return "*-1\r\n"
+			}
+		}
+
+		reply := fmt.Sprintf("*%d\r\n", len(queued))
+
+		for _, queuedCommand := range queued {
+			reply += s.run(fc, queuedCommand[0], queuedCommand[1:])
+		}
+
+		return reply
+	default:
+		return s.run(fc, command, args)
+	}
+}
+
+// run executes the command other than the authentication and the transactions. The caller must hold the lock.
+//
+//nolint:gocyclo,cyclop
+func (s *fakeRedisServer) run(fc *fakeRedisConn, command string, args []string) string {
+	switch command {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, found := s.get(args[0])
+		if !found {
+			return "$-1\r\n"
+		}
+
+		return bulkString(value.data)
+	case "SET":
+		value := fakeRedisValue{data: args[1]}
+		onlyIfAbsent := false
+
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				onlyIfAbsent = true
+			case "PX":
+				i++
+
+				ttl, err := strconv.ParseInt(args[i], 10, 64)
+				if err != nil || ttl <= 0 {
+					return "-ERR invalid expire time in 'set' command\r\n"
+				}
+
+				value.expiresAt = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+			default:
+				return "-ERR syntax error\r\n"
+			}
+		}
+
+		if _, found := s.get(args[0]); found && onlyIfAbsent {
+			return "$-1\r\n"
+		}
+
+		s.strings[args[0]] = value
+		s.versions[args[0]]++
+
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+
+		for _, key := range args {
+			if _, found := s.get(key); found {
+				delete(s.strings, key)
+				s.versions[key]++
+
+				deleted++
+			}
+		}
+
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "EXISTS":
+		exists := 0
+
+		for _, key := range args {
+			if _, found := s.get(key); found {
+				exists++
+			}
+		}
+
+		return fmt.Sprintf(":%d\r\n", exists)
+	case "SADD":
+		if _, found := s.sets[args[0]]; !found {
+			s.sets[args[0]] = make(map[string]struct{})
+		}
+
+		added := 0
+
+		for _, member := range args[1:] {
+			if _, found := s.sets[args[0]][member]; !found {
+				s.sets[args[0]][member] = struct{}{}
+				s.versions[args[0]]++
+
+				added++
+			}
+		}
+
+		return fmt.Sprintf(":%d\r\n", added)
+	case "SREM":
+		removed := 0
+
+		for _, member := range args[1:] {
+			if _, found := s.sets[args[0]][member]; found {
+				delete(s.sets[args[0]], member)
+				s.versions[args[0]]++
+
+				removed++
+			}
+		}
+
+		if len(s.sets[args[0]]) == 0 {
+			delete(s.sets, args[0])
+		}
+
+		return fmt.Sprintf(":%d\r\n", removed)
+	case "SMEMBERS":
+		return bulkStringArray(keysOf(s.sets[args[0]]))
+	case "SCAN":
+		pattern := "*"
+
+		for i := 1; i+1 < len(args); i += 2 {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+
+		keys := make([]string, 0)
+
+		for key := range s.strings {
+			if _, found := s.get(key); found && globMatch(pattern, key) {
+				keys = append(keys, key)
+			}
+		}
+
+		// The whole keyspace is returned in a single page.
+		return "*2\r\n" + bulkString("0") + bulkStringArray(keys)
+	case "PUBLISH":
+		subscribers := s.subscribers[args[0]]
+		message := "*3\r\n" + bulkString("message") + bulkString(args[0]) + bulkString(args[1])
+
+		for subscriber := range subscribers {
+			subscriber.write(message)
+		}
+
+		return fmt.Sprintf(":%d\r\n", len(subscribers))
+	case "SUBSCRIBE":
+		if _, found := s.subscribers[args[0]]; !found {
+			s.subscribers[args[0]] = make(map[*fakeRedisConn]struct{})
+		}
+
+		s.subscribers[args[0]][fc] = struct{}{}
+
+		return "*3\r\n" + bulkString("subscribe") + bulkString(args[0]) + ":1\r\n"
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", command)
+	}
+}
+
+// get returns the value of the key, deleting it if expired. The caller must hold the lock.
+func (s *fakeRedisServer) get(key string) (fakeRedisValue, bool) {
+	value, found := s.strings[key]
+	if !found {
+		return value, false
+	}
+
+	if !value.expiresAt.IsZero() && !time.Now().Before(value.expiresAt) {
+		delete(s.strings, key)
+		s.versions[key]++
+
+		return value, false
+	}
+
+	return value, true
+}
+
+func bulkString(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func bulkStringArray(elements []string) string {
+	reply := fmt.Sprintf("*%d\r\n", len(elements))
+
+	for _, element := range elements {
+		reply += bulkString(element)
+	}
+
+	return reply
+}
+
+// globMatch matches the glob-style patterns of SCAN supporting '*', '?' and the backslash escapes.
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if globMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+
+	return len(s) == 0
+}
@@ -0,0 +1,353 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// testCacheConformance checks the behavior shared by all the Cache implementations.
+func testCacheConformance(t *testing.T, cache Cache) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := cache.Watch(ctx)
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+	other := types.NamespacedName{Namespace: "test", Name: "other"}
+	expiresAt := time.Now().Add(time.Minute).Unix()
+
+	cache.Set("private/a.example.com", &echo, 0)
+
+	owner, found := cache.Get("private/a.example.com")
+	assert.True(t, found)
+	assert.Equal(t, echo, *owner)
+	assert.True(t, cache.KeyExists("private/a.example.com"))
+	assert.True(t, *cache.IsKeyPersisted("private/a.example.com"))
+	assert.Equal(t, Event{Type: EventSet, Entry: Entry{Key: "private/a.example.com", Owner: echo}}, receiveEvent(t, events))
+
+	// Reserving an existing key returns its owner.
+	owner, reserved, err := cache.Reserve("private/a.example.com", &other, expiresAt)
+	require.Nil(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, echo, *owner)
+
+	owner, reserved, err = cache.Reserve("private/b.example.com", &other, expiresAt)
+	require.Nil(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, other, *owner)
+	assert.False(t, *cache.IsKeyPersisted("private/b.example.com"))
+	assert.Equal(t, Event{Type: EventSet, Entry: Entry{Key: "private/b.example.com", Owner: other, ExpiresAt: expiresAt}},
+		receiveEvent(t, events))
+
+	entry, found := cache.GetEntry("private/b.example.com")
+	assert.True(t, found)
+	assert.Equal(t, []Entry{{Key: "private/b.example.com", Owner: other, ExpiresAt: expiresAt}}, withoutModifiedAt(t, *entry))
+
+	cache.Set("public/a.example.com", &echo, 0)
+	<-events
+
+	assert.Equal(t, []Entry{
+		{Key: "private/a.example.com", Owner: echo},
+		{Key: "private/b.example.com", Owner: other, ExpiresAt: expiresAt},
+		{Key: "public/a.example.com", Owner: echo},
+	}, withoutModifiedAt(t, cache.Entries()...))
+	assert.Equal(t, []string{"private/a.example.com", "public/a.example.com"}, cache.GetKeysByOwner(echo))
+	assert.Len(t, cache.GetKeysByNamespace("test"), 3)
+
+	// Overwriting an entry moves it to the new owner.
+	cache.Set("public/a.example.com", &other, 0)
+	<-events
+
+	assert.Equal(t, []string{"private/a.example.com"}, cache.GetKeysByOwner(echo))
+	assert.Equal(t, []string{"private/b.example.com", "public/a.example.com"}, cache.GetKeysByOwner(other))
+
+	cache.Delete("private/a.example.com")
+
+	assert.Equal(t, Event{Type: EventDelete, Entry: Entry{Key: "private/a.example.com"}}, receiveEvent(t, events))
+	assert.Nil(t, cache.IsKeyPersisted("private/a.example.com"))
+	assert.Empty(t, cache.GetKeysByOwner(echo))
+
+	assert.Equal(t, 1, cache.DeleteByPrefix("public/"))
+	<-events
+	assert.Equal(t, []string{"private/b.example.com"}, cache.GetKeysByNamespace("test"))
+
+	assert.Equal(t, 1, cache.DeleteByOwner(other))
+	<-events
+	assert.Empty(t, cache.Entries())
+
+	cache.Set("private/c.example.com", &echo, 0)
+	<-events
+
+	assert.Equal(t, 1, cache.DeleteByNamespace("test"))
+	<-events
+	assert.False(t, cache.KeyExists("private/c.example.com"))
+
+	// The claimants of a conflicted entry are released one by one.
+	cache.Set("private/d.example.com", &echo, 0)
+	<-events
+
+	claimants, found := cache.AddClaimant("private/d.example.com", other)
+	assert.True(t, found)
+	assert.Equal(t, []types.NamespacedName{echo, other}, claimants)
+	assert.Equal(t, Event{Type: EventSet, Entry: Entry{Key: "private/d.example.com", Owner: echo, Claimants: []types.NamespacedName{other}}},
+		receiveEvent(t, events))
+
+	claimants, _ = cache.AddClaimant("private/d.example.com", other)
+	assert.Equal(t, []types.NamespacedName{echo, other}, claimants)
+
+	unknown := types.NamespacedName{Namespace: "test", Name: "unknown"}
+	assert.Equal(t, []types.NamespacedName{echo, other}, cache.Release("private/d.example.com", unknown))
+
+	// Releasing the owner promotes the next claimant.
+	assert.Equal(t, []types.NamespacedName{other}, cache.Release("private/d.example.com", echo))
+	assert.Equal(t, Event{Type: EventSet, Entry: Entry{Key: "private/d.example.com", Owner: other}}, receiveEvent(t, events))
+	assert.Empty(t, cache.GetKeysByOwner(echo))
+	assert.Equal(t, []string{"private/d.example.com"}, cache.GetKeysByOwner(other))
+
+	assert.Empty(t, cache.Release("private/d.example.com", other))
+	<-events
+	assert.False(t, cache.KeyExists("private/d.example.com"))
+
+	_, found = cache.AddClaimant("private/d.example.com", other)
+	assert.False(t, found)
+
+	// A quarantined entry is replaced by setting the key.
+	cache.Quarantine("private/e.example.com", echo, expiresAt)
+	assert.Equal(t, Event{Type: EventSet, Entry: Entry{Key: "private/e.example.com", Owner: echo, ExpiresAt: expiresAt, Quarantined: true}},
+		receiveEvent(t, events))
+
+	cache.Set("private/e.example.com", &other, 0)
+	<-events
+
+	entry, _ = cache.GetEntry("private/e.example.com")
+	assert.False(t, entry.Quarantined)
+	assert.Empty(t, cache.GetKeysByOwner(echo))
+
+	// The events channel is closed once the context is done.
+	cancel()
+
+	for range events {
+	}
+}
+
+// receiveEvent returns the next event without the modification time, after checking it's set on SET events.
+func receiveEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+
+	event := <-events
+
+	if event.Type == EventSet {
+		assert.NotZero(t, event.Entry.ModifiedAt)
+	}
+
+	return event.withoutModifiedAt()
+}
+
+func (e Event) withoutModifiedAt() Event {
+	e.Entry.ModifiedAt = 0
+
+	return e
+}
+
+// withoutModifiedAt returns the entries without the modification time, after checking it's set.
+func withoutModifiedAt(t *testing.T, entries ...Entry) []Entry {
+	t.Helper()
+
+	stripped := make([]Entry, 0, len(entries))
+
+	for _, entry := range entries {
+		assert.NotZero(t, entry.ModifiedAt)
+
+		entry.ModifiedAt = 0
+		stripped = append(stripped, entry)
+	}
+
+	return stripped
+}
+
+func TestMemoryCacheConformance(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer func() { cache.CleanUpStopChan <- true }()
+
+	testCacheConformance(t, cache)
+}
+
+func TestRedisCacheConformance(t *testing.T) {
+	server := newFakeRedisServer(t, "secret")
+
+	cache, err := NewRedisCache(config.Redis{Address: server.address(), Password: "secret", KeyPrefix: "test:"})
+	require.Nil(t, err)
+
+	testCacheConformance(t, cache)
+}
+
+func TestRedisCacheExpiry(t *testing.T) {
+	server := newFakeRedisServer(t, "")
+
+	cache, err := NewRedisCache(config.Redis{Address: server.address()})
+	require.Nil(t, err)
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+
+	_, reserved, err := cache.Reserve("private/a.example.com", &echo, time.Now().Add(time.Second).Unix())
+	require.Nil(t, err)
+	assert.True(t, reserved)
+
+	// Expired reservations are not stored at all.
+	cache.Set("private/b.example.com", &echo, time.Now().Add(-time.Second).Unix())
+	assert.False(t, cache.KeyExists("private/b.example.com"))
+
+	_, reserved, err = cache.Reserve("private/b.example.com", &echo, time.Now().Add(-time.Second).Unix())
+	assert.ErrorIs(t, err, errExpired)
+	assert.False(t, reserved)
+	assert.False(t, cache.KeyExists("private/b.example.com"))
+
+	time.Sleep(1100 * time.Millisecond)
+
+	assert.False(t, cache.KeyExists("private/a.example.com"))
+	// The index members of the expired entries are pruned.
+	assert.Empty(t, cache.GetKeysByOwner(echo))
+
+	_, reserved, err = cache.Reserve("private/a.example.com", &echo, 0)
+	require.Nil(t, err)
+	assert.True(t, reserved)
+}
+
+func TestRedisCacheAuthentication(t *testing.T) {
+	server := newFakeRedisServer(t, "secret")
+
+	_, err := NewRedisCache(config.Redis{Address: server.address(), Password: "wrong"})
+	assert.NotNil(t, err)
+}
+
+func TestRedisCacheWatchAcrossReplicas(t *testing.T) {
+	server := newFakeRedisServer(t, "")
+
+	writer, err := NewRedisCache(config.Redis{Address: server.address(), KeyPrefix: "test:"})
+	require.Nil(t, err)
+
+	watcher, err := NewRedisCache(config.Redis{Address: server.address(), KeyPrefix: "test:"})
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := watcher.Watch(ctx)
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+
+	writer.Set("private/a.example.com", &echo, 0)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, Event{Type: EventSet, Entry: Entry{Key: "private/a.example.com", Owner: echo}}, event.withoutModifiedAt())
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	owner, found := watcher.Get("private/a.example.com")
+	assert.True(t, found)
+	assert.Equal(t, echo, *owner)
+}
+
+func TestRedisCacheConcurrentReplicas(t *testing.T) {
+	server := newFakeRedisServer(t, "")
+
+	replicas := make([]*RedisCache, 8)
+
+	for i := range replicas {
+		replica, err := NewRedisCache(config.Redis{Address: server.address(), KeyPrefix: "test:"})
+		require.Nil(t, err)
+
+		replicas[i] = replica
+	}
+
+	// runConcurrently runs the function for each replica concurrently.
+	runConcurrently := func(run func(i int, replica *RedisCache)) {
+		var wg sync.WaitGroup
+
+		for i, replica := range replicas {
+			wg.Add(1)
+
+			go func(i int, replica *RedisCache) {
+				defer wg.Done()
+
+				run(i, replica)
+			}(i, replica)
+		}
+
+		wg.Wait()
+	}
+
+	claimants := make([]types.NamespacedName, len(replicas))
+	for i := range claimants {
+		claimants[i] = types.NamespacedName{Namespace: fmt.Sprintf("test-%d", i), Name: "echo"}
+	}
+
+	var mu sync.Mutex
+
+	reservations := 0
+
+	runConcurrently(func(i int, replica *RedisCache) {
+		_, reserved, err := replica.Reserve("private/a.example.com", &claimants[i], 0)
+		assert.Nil(t, err)
+
+		if reserved {
+			mu.Lock()
+			reservations++
+			mu.Unlock()
+		}
+	})
+
+	assert.Equal(t, 1, reservations)
+
+	runConcurrently(func(i int, replica *RedisCache) {
+		_, found := replica.AddClaimant("private/a.example.com", claimants[i])
+		assert.True(t, found)
+	})
+
+	entry, found := replicas[0].GetEntry("private/a.example.com")
+	require.True(t, found)
+	assert.ElementsMatch(t, claimants, entry.AllClaimants())
+
+	runConcurrently(func(i int, replica *RedisCache) {
+		replica.Release("private/a.example.com", claimants[i])
+	})
+
+	assert.False(t, replicas[0].KeyExists("private/a.example.com"))
+
+	for _, claimant := range claimants {
+		assert.Empty(t, replicas[0].GetKeysByNamespace(claimant.Namespace))
+	}
+
+	server.mu.Lock()
+	assert.Empty(t, server.sets)
+	server.mu.Unlock()
+}
+
+func TestRedisCacheLookupEntryUnavailable(t *testing.T) {
+	server := newFakeRedisServer(t, "")
+
+	cache, err := NewRedisCache(config.Redis{Address: server.address()})
+	require.Nil(t, err)
+
+	server.listener.Close()
+	cache.client.conn.close()
+
+	_, err = cache.LookupEntry("private/a.example.com")
+	assert.NotNil(t, err)
+
+	// GetEntry reports the errors as missing entries.
+	_, found := cache.GetEntry("private/a.example.com")
+	assert.False(t, found)
+}
@@ -0,0 +1,282 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errTransactionAborted is returned if a watched key is modified before the transaction is executed.
+var errTransactionAborted = errors.New("redis transaction aborted")
+
+// respError is an error reply of the server.
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+// respConn is a connection speaking the Redis serialization protocol (RESP2).
+type respConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialResp(address, password string, db int, timeout time.Duration) (*respConn, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &respConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	if password != "" {
+		if _, err := rc.do("AUTH", password); err != nil {
+			rc.close()
+
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if db != 0 {
+		if _, err := rc.do("SELECT", strconv.Itoa(db)); err != nil {
+			rc.close()
+
+			return nil, fmt.Errorf("failed to select the database: %w", err)
+		}
+	}
+
+	return rc, nil
+}
+
+// do sends the command and returns its reply, an error reply is returned as respError.
+func (rc *respConn) do(args ...string) (interface{}, error) {
+	if err := rc.write(args...); err != nil {
+		return nil, err
+	}
+
+	reply, err := rc.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if replyErr, ok := reply.(respError); ok {
+		return nil, replyErr
+	}
+
+	return reply, nil
+}
+
+// transaction watches the keys and passes the connection to read, which returns the commands executed atomically by
+// MULTI/EXEC. Nothing is executed if read returns no commands. It returns errTransactionAborted if a watched key is
+// modified meanwhile.
+func (rc *respConn) transaction(keys []string, read func(conn *respConn) ([][]string, error)) ([]interface{}, error) {
+	if _, err := rc.do(append([]string{"WATCH"}, keys...)...); err != nil {
+		return nil, err
+	}
+
+	commands, err := read(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(commands) == 0 {
+		_, err := rc.do("UNWATCH")
+
+		return nil, err
+	}
+
+	if _, err := rc.do("MULTI"); err != nil {
+		return nil, err
+	}
+
+	for _, command := range commands {
+		if _, err := rc.do(command...); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := rc.do("EXEC")
+	if err != nil {
+		return nil, err
+	}
+
+	if reply == nil {
+		return nil, errTransactionAborted
+	}
+
+	replies, _ := reply.([]interface{})
+
+	for _, reply := range replies {
+		if replyErr, ok := reply.(respError); ok {
+			return nil, replyErr
+		}
+	}
+
+	return replies, nil
+}
+
+func (rc *respConn) write(args ...string) error {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "*%d\r\n", len(args))
+
+	for _, arg := range args {
+		fmt.Fprintf(&builder, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	_, err := io.WriteString(rc.conn, builder.String())
+
+	return err
+}
+
+// read returns a reply as a string, respError, int64, nil or []interface{} of them.
+func (rc *respConn) read() (interface{}, error) {
+	line, err := rc.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty resp reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return respError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, err
+		}
+
+		data := make([]byte, length+2)
+
+		if _, err := io.ReadFull(rc.reader, data); err != nil {
+			return nil, err
+		}
+
+		return string(data[:length]), nil
+	case '*':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, err
+		}
+
+		elements := make([]interface{}, 0, length)
+
+		for i := 0; i < length; i++ {
+			element, err := rc.read()
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, element)
+		}
+
+		return elements, nil
+	default:
+		return nil, fmt.Errorf("unknown resp reply type %q", line[0])
+	}
+}
+
+func (rc *respConn) close() {
+	rc.conn.Close()
+}
+
+// respClient sends the commands over a single connection, which is re-established on network errors.
+type respClient struct {
+	address  string
+	password string
+	db       int
+	timeout  time.Duration
+	conn     *respConn
+	mu       sync.Mutex
+}
+
+func (c *respClient) do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+
+	// Retry once, as the connection might have been closed by the server since the last command.
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			c.conn, err = dialResp(c.address, c.password, c.db, c.timeout)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var reply interface{}
+
+		if err = c.conn.conn.SetDeadline(time.Now().Add(c.timeout)); err == nil {
+			reply, err = c.conn.do(args...)
+		}
+
+		var replyErr respError
+		if err == nil || errors.As(err, &replyErr) {
+			return reply, err
+		}
+
+		c.conn.close()
+		c.conn = nil
+	}
+
+	return nil, err
+}
+
+// transaction runs respConn.transaction holding the connection exclusively. The connection is closed on errors other
+// than an aborted transaction, as its state, e.g. the watched keys, is unknown.
+func (c *respClient) transaction(keys []string, read func(conn *respConn) ([][]string, error)) ([]interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := dialResp(c.address, c.password, c.db, c.timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		c.conn = conn
+	}
+
+	if err := c.conn.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		c.conn.close()
+		c.conn = nil
+
+		return nil, err
+	}
+
+	replies, err := c.conn.transaction(keys, read)
+	if err != nil && !errors.Is(err, errTransactionAborted) {
+		c.conn.close()
+		c.conn = nil
+	}
+
+	return replies, err
+}
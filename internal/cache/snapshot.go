@@ -0,0 +1,145 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// SaveSnapshot writes all the entries to the file. The file is replaced atomically, so a crash while writing
+// never leaves a partial snapshot behind.
+func SaveSnapshot(c Cache, path string) error {
+	data, err := json.Marshal(snapshot{Version: snapshotVersion, Entries: c.Entries()})
+	if err != nil {
+		return fmt.Errorf("failed to encode the cache snapshot: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create the cache snapshot directory: %w", err)
+	}
+
+	// The temporary file is created in the same directory, as renaming across file systems is not atomic.
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create the cache snapshot file: %w", err)
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+
+		return fmt.Errorf("failed to write the cache snapshot file: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+
+		return fmt.Errorf("failed to sync the cache snapshot file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close the cache snapshot file: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace the cache snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot adds the entries of the file to the cache and returns the number of loaded entries.
+// Expired entries are skipped and a missing file is not an error.
+func LoadSnapshot(c Cache, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to read the cache snapshot file: %w", err)
+	}
+
+	loaded := snapshot{}
+
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return 0, fmt.Errorf("failed to decode the cache snapshot: %w", err)
+	}
+
+	if loaded.Version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported cache snapshot version %d", loaded.Version)
+	}
+
+	now := time.Now().Unix()
+	count := 0
+
+	for _, entry := range loaded.Entries {
+		if entry.ExpiresAt > 0 && now >= entry.ExpiresAt {
+			continue
+		}
+
+		owner := entry.Owner
+
+		if entry.Quarantined {
+			c.Quarantine(entry.Key, owner, entry.ExpiresAt)
+		} else {
+			c.Set(entry.Key, &owner, entry.ExpiresAt)
+		}
+
+		for _, claimant := range entry.Claimants {
+			c.AddClaimant(entry.Key, claimant)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// StartSnapshotter saves a snapshot to the file every interval and once more when the context is done.
+// It blocks until the context is done, so it can be run by the manager.
+func StartSnapshotter(ctx context.Context, c Cache, path string, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid cache snapshot interval %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := SaveSnapshot(c, path); err != nil {
+				logger.Error(err, "failed to save the cache snapshot")
+			}
+		case <-ctx.Done():
+			if err := SaveSnapshot(c, path); err != nil {
+				logger.Error(err, "failed to save the cache snapshot")
+			}
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Cache stores the owner of each key, i.e. the ingressClassName/FQDN pairs and the rate limit keys.
+// The entries expiring at zero are persisted, the others are reservations made by the webhook.
+type Cache interface {
+	// Set sets the entry regardless of its current owner.
+	Set(key string, value *types.NamespacedName, expirationUnixTime int64)
+	// Reserve sets the entry only if the key does not exist, otherwise it returns the current owner.
+	// Unlike the other methods, it returns the backend errors, so the caller can fail closed.
+	Reserve(key string, value *types.NamespacedName, expirationUnixTime int64) (*types.NamespacedName, bool, error)
+	Get(key string) (*types.NamespacedName, bool)
+	// GetEntry returns a copy of the entry.
+	GetEntry(key string) (*Entry, bool)
+	// LookupEntry returns a copy of the entry, or nil if the key does not exist. Unlike GetEntry, it returns the
+	// backend errors, so the ownership rules can fail closed.
+	LookupEntry(key string) (*Entry, error)
+	Delete(key string)
+	// Quarantine sets an entry expiring at the given time, which keeps the key released by the owner for its
+	// namespace.
+	Quarantine(key string, owner types.NamespacedName, expirationUnixTime int64)
+	// AddClaimant records another persisted owner of a conflicted entry and returns all the claimants, the owner
+	// first. It returns false if the entry does not exist.
+	AddClaimant(key string, claimant types.NamespacedName) ([]types.NamespacedName, bool)
+	// Release removes the claimant from the entry and returns the remaining claimants. If the owner is released, the
+	// next claimant becomes the owner, and the entry is deleted once no claimant remains.
+	Release(key string, claimant types.NamespacedName) []types.NamespacedName
+	// DeleteByPrefix deletes all the entries whose key starts with the prefix and returns the number of deleted entries.
+	DeleteByPrefix(prefix string) int
+	KeyExists(key string) bool
+	IsKeyPersisted(key string) *bool
+	// Entries returns a copy of all the entries sorted by key.
+	Entries() []Entry
+	// GetKeysByOwner returns the keys owned by the object.
+	GetKeysByOwner(owner types.NamespacedName) []string
+	// GetKeysByNamespace returns the keys owned by the objects of the namespace.
+	GetKeysByNamespace(namespace string) []string
+	// DeleteByOwner deletes all the entries owned by the object and returns the number of deleted entries.
+	DeleteByOwner(owner types.NamespacedName) int
+	// DeleteByNamespace deletes all the entries owned by the objects of the namespace and returns the number of
+	// deleted entries.
+	DeleteByNamespace(namespace string) int
+	// Watch returns the changes of the entries until the context is done. Slow watchers miss events.
+	Watch(ctx context.Context) <-chan Event
+}
+
+// Entry is a copy of a cache entry.
+type Entry struct {
+	Key       string               `json:"key"`
+	Owner     types.NamespacedName `json:"owner"`
+	ExpiresAt int64                `json:"expiresAt"` // zero if persisted
+	// ModifiedAt is the unix time the entry was last set.
+	ModifiedAt int64 `json:"modifiedAt,omitempty"`
+	// Claimants are the other persisted owners of a conflicted entry. Unlike the owner, they are not indexed.
+	Claimants []types.NamespacedName `json:"claimants,omitempty"`
+	// Quarantined is set for the entries of released keys, the owner is the object that released the key.
+	Quarantined bool `json:"quarantined,omitempty"`
+}
+
+// AllClaimants returns the owner followed by the other claimants.
+func (e Entry) AllClaimants() []types.NamespacedName {
+	return append([]types.NamespacedName{e.Owner}, e.Claimants...)
+}
+
+type EventType string
+
+const (
+	EventSet    EventType = "SET"
+	EventDelete EventType = "DELETE"
+)
+
+// Event is a change of an entry. Only the key of deleted entries is set.
+type Event struct {
+	Type  EventType `json:"type"`
+	Entry Entry     `json:"entry"`
+}
+
+// watchBufferSize is the number of events buffered per watcher.
+const watchBufferSize = 100
@@ -18,6 +18,19 @@ package config
 
 import (
 	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RuleModeWarn makes a rule admit the request and report its findings as admission warnings.
+	RuleModeWarn = "warn"
+	// RuleModeEnforce makes a rule deny the request on any finding.
+	RuleModeEnforce = "enforce"
+
+	// CacheBackendMemory keeps the cache in the memory of each replica.
+	CacheBackendMemory = "memory"
+	// CacheBackendRedis keeps the cache in a Redis-protocol server shared by the replicas.
+	CacheBackendRedis = "redis"
 )
 
 var config Config
@@ -25,18 +38,259 @@ var config Config
 type Config struct {
 	Cache          Cache    `yaml:"cache"`
 	IngressClasses []string `yaml:"ingressClasses"`
-	Webhook        Webhook  `yaml:"webhook"`
+	// DefaultIngressClass is used for HTTPProxy objects which set neither an ingress class annotation nor spec.ingressClassName.
+	DefaultIngressClass   string                `yaml:"defaultIngressClass"`
+	IngressClassDiscovery IngressClassDiscovery `yaml:"ingressClassDiscovery"`
+	Rules                 Rules                 `yaml:"rules"`
+	Webhook               Webhook               `yaml:"webhook"`
 }
 
 type Cache struct {
-	CleanUpIntervalSecond int `yaml:"cleanUpIntervalSecond"`
-	EntryTtlSecond        int `yaml:"entryTtlSecond"`
+	// Backend is one of "memory" or "redis", defaults to "memory".
+	Backend               string        `yaml:"backend"`
+	Redis                 Redis         `yaml:"redis"`
+	CleanUpIntervalSecond int           `yaml:"cleanUpIntervalSecond"`
+	EntryTtlSecond        int           `yaml:"entryTtlSecond"`
+	Snapshot              CacheSnapshot `yaml:"snapshot"`
+	// ResyncIntervalSecond is the interval of repairing the cache drift from the HTTPProxy objects, zero disables it.
+	ResyncIntervalSecond int `yaml:"resyncIntervalSecond"`
+	// Stateless looks up the owners of the FQDNs via a field index over the HTTPProxy informer, so the cache only
+	// keeps the reservations made by the webhook.
+	Stateless bool `yaml:"stateless"`
+	// QuarantineSecond is the period a released FQDN can only be reclaimed by the namespace that released it, zero
+	// disables it. It requires the redis backend or the snapshot, which keep the quarantines across restarts.
+	QuarantineSecond int `yaml:"quarantineSecond"`
+}
+
+// Redis configures the Redis-protocol server of the redis cache backend.
+type Redis struct {
+	Address  string `yaml:"address"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+	// KeyPrefix is prepended to all the keys, so multiple deployments can share a server.
+	KeyPrefix         string `yaml:"keyPrefix"`
+	DialTimeoutSecond int    `yaml:"dialTimeoutSecond"`
+}
+
+// CacheSnapshot configures persisting the cache to a local file, which is reloaded on boot and reconciled against
+// the HTTPProxy objects once the informers are synced.
+type CacheSnapshot struct {
+	Enabled        bool   `yaml:"enabled"`
+	Path           string `yaml:"path"`
+	IntervalSecond int    `yaml:"intervalSecond"`
+}
+
+// IngressClassDiscovery configures deriving the valid ingressClassNames from the IngressClass objects of the cluster.
+// A non-empty ingressClasses list overrides the discovered ingressClassNames.
+type IngressClassDiscovery struct {
+	Enabled bool `yaml:"enabled"`
+	// ControllerName is the spec.controller value of the IngressClass objects to consider, e.g. "projectcontour.io/contour".
+	ControllerName string `yaml:"controllerName"`
+}
+
+type Rules struct {
+	ServiceReference        ServiceReference        `yaml:"serviceReference"`
+	TLSSecret               TLSSecret               `yaml:"tlsSecret"`
+	TLSPolicies             []TLSPolicy             `yaml:"tlsPolicies"`
+	Bounds                  Bounds                  `yaml:"bounds"`
+	HostRewrite             HostRewrite             `yaml:"hostRewrite"`
+	NamespaceIngressClasses NamespaceIngressClasses `yaml:"namespaceIngressClasses"`
+	IngressClassTransitions IngressClassTransitions `yaml:"ingressClassTransitions"`
+	IngressClassAccess      []IngressClassAccess    `yaml:"ingressClassAccess"`
+	RateLimit               RateLimit               `yaml:"rateLimit"`
+	FqdnHierarchy           FqdnHierarchy           `yaml:"fqdnHierarchy"`
+}
+
+// FqdnHierarchy configures the hierarchical FQDN ownership, in which the subdomains of a claimed FQDN are reserved for
+// the namespace of its owner.
+type FqdnHierarchy struct {
+	Enabled bool `yaml:"enabled"`
+	// DelegationAnnotation lists the namespaces, separated by commas, allowed to claim the subdomains of the FQDN of the
+	// annotated HTTPProxy object, "*" allows all the namespaces.
+	DelegationAnnotation string `yaml:"delegationAnnotation"`
+}
+
+// ServiceReference configures the rule validating services referenced by routes and tcpproxy.
+type ServiceReference struct {
+	Enabled bool `yaml:"enabled"`
+	// Mode is one of "warn" or "enforce", defaults to "warn".
+	Mode string `yaml:"mode"`
+	// AllowExternalName permits referencing services of type ExternalName.
+	AllowExternalName bool `yaml:"allowExternalName"`
+}
+
+// TLSSecret configures the rule validating the secret referenced by spec.virtualhost.tls.secretName.
+type TLSSecret struct {
+	Enabled bool `yaml:"enabled"`
+	// Mode is one of "warn" or "enforce", defaults to "warn".
+	Mode string `yaml:"mode"`
+	// ExpiryWarningDays is the number of days before the certificate expiry from which a warning is returned.
+	ExpiryWarningDays int `yaml:"expiryWarningDays"`
+}
+
+// TLSPolicy configures the TLS requirements enforced on the HTTPProxy objects of an ingress class.
+type TLSPolicy struct {
+	IngressClassName string `yaml:"ingressClassName"`
+	// RequireTLS requires spec.virtualhost.tls to be set on root HTTPProxy objects.
+	RequireTLS bool `yaml:"requireTLS"`
+	// ForbidPermitInsecure forbids setting permitInsecure on routes.
+	ForbidPermitInsecure bool `yaml:"forbidPermitInsecure"`
+	// MinimumProtocolVersion is the lowest allowed spec.virtualhost.tls.minimumProtocolVersion, e.g. "1.3".
+	MinimumProtocolVersion string `yaml:"minimumProtocolVersion"`
+	// RequireClientValidation requires spec.virtualhost.tls.clientValidation to be set.
+	RequireClientValidation bool `yaml:"requireClientValidation"`
+}
+
+// Bounds configures the rule limiting the timeout, retry, health check and load balancer policies.
+type Bounds struct {
+	// Mode is one of "warn" or "enforce", defaults to "warn".
+	Mode     string         `yaml:"mode"`
+	Policies []BoundsPolicy `yaml:"policies"`
+}
+
+// BoundsPolicy configures the limits applied on the HTTPProxy objects of an ingress class.
+// A zero value disables the corresponding limit.
+type BoundsPolicy struct {
+	IngressClassName               string   `yaml:"ingressClassName"`
+	MaxResponseTimeoutSecond       int      `yaml:"maxResponseTimeoutSecond"`
+	MaxIdleTimeoutSecond           int      `yaml:"maxIdleTimeoutSecond"`
+	MaxIdleConnectionTimeoutSecond int      `yaml:"maxIdleConnectionTimeoutSecond"`
+	MaxRetryCount                  int64    `yaml:"maxRetryCount"`
+	MaxPerTryTimeoutSecond         int      `yaml:"maxPerTryTimeoutSecond"`
+	MinHealthCheckIntervalSecond   int64    `yaml:"minHealthCheckIntervalSecond"`
+	MaxHealthCheckTimeoutSecond    int64    `yaml:"maxHealthCheckTimeoutSecond"`
+	LoadBalancerStrategies         []string `yaml:"loadBalancerStrategies"`
+}
+
+// HostRewrite configures the rule preventing Host header rewrites and redirects into hosts owned by other namespaces.
+type HostRewrite struct {
+	Enabled bool `yaml:"enabled"`
+	// AllowedHosts are the hosts any namespace can target, a leading "*." matches all the subdomains.
+	AllowedHosts []string `yaml:"allowedHosts"`
+	// DomainDelegations grants namespaces all the hosts under a domain.
+	DomainDelegations []DomainDelegation `yaml:"domainDelegations"`
+}
+
+// DomainDelegation grants the namespaces the domain and all of its subdomains.
+type DomainDelegation struct {
+	Domain     string   `yaml:"domain"`
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// NamespaceIngressClasses configures the ingressClassNames each namespace is permitted to use.
+// The rule is disabled if no policy is set.
+type NamespaceIngressClasses struct {
+	Policies []NamespaceIngressClassPolicy `yaml:"policies"`
+	// DefaultIngressClasses are permitted in the namespaces not selected by any policy.
+	DefaultIngressClasses []string `yaml:"defaultIngressClasses"`
+}
+
+// NamespaceIngressClassPolicy permits the namespaces selected by the namespace selector to use the ingressClassNames.
+// Note that the label keys are lower-cased when the config is read.
+type NamespaceIngressClassPolicy struct {
+	NamespaceSelector metav1.LabelSelector `yaml:"namespaceSelector"`
+	IngressClasses    []string             `yaml:"ingressClasses"`
+}
+
+// IngressClassTransitions configures the allowed ingressClassName changes of existing HTTPProxy objects.
+type IngressClassTransitions struct {
+	Enabled bool `yaml:"enabled"`
+	// ApprovalAnnotation holds the approved target ingressClassName and can only be set by the approver groups.
+	ApprovalAnnotation string   `yaml:"approvalAnnotation"`
+	ApproverGroups     []string `yaml:"approverGroups"`
+	// Transitions lists the allowed ingressClassName changes, any other change is denied.
+	Transitions []IngressClassTransition `yaml:"transitions"`
+}
+
+// IngressClassTransition is an allowed ingressClassName change.
+type IngressClassTransition struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	// RequireApproval requires the approval annotation to be set to the target ingressClassName.
+	RequireApproval bool `yaml:"requireApproval"`
+}
+
+// IngressClassAccess restricts the ingressClassName to the requests of the users and the members of the groups.
+// The ingressClassNames not listed are not restricted.
+type IngressClassAccess struct {
+	IngressClassName string   `yaml:"ingressClassName"`
+	Users            []string `yaml:"users"`
+	Groups           []string `yaml:"groups"`
+}
+
+// RateLimit configures the rule validating the global rate limit policies.
+type RateLimit struct {
+	// Mode is one of "warn" or "enforce", defaults to "warn".
+	Mode         string                `yaml:"mode"`
+	Descriptors  RateLimitDescriptors  `yaml:"descriptors"`
+	KeyOwnership RateLimitKeyOwnership `yaml:"keyOwnership"`
+	Budgets      RateLimitBudgets      `yaml:"budgets"`
+	Local        LocalRateLimit        `yaml:"local"`
+}
+
+// RateLimitDescriptors configures the rule validating the global rate limit descriptor keys and rates.
+type RateLimitDescriptors struct {
+	Enabled bool `yaml:"enabled"`
+	// Mode is one of "warn" or "enforce", defaults to "enforce".
+	Mode string `yaml:"mode"`
+	// MaxRequestsPerSecond limits the rate of a descriptor normalized to requests per second, zero disables the limit.
+	MaxRequestsPerSecond int64 `yaml:"maxRequestsPerSecond"`
+}
+
+// RateLimitBudgets configures the sum of the global rate limits each namespace is permitted to declare across all
+// its HTTPProxy objects. The rule is disabled if neither a policy nor a default budget is set.
+type RateLimitBudgets struct {
+	// Mode is one of "warn" or "enforce", defaults to "enforce".
+	Mode     string                  `yaml:"mode"`
+	Policies []RateLimitBudgetPolicy `yaml:"policies"`
+	// DefaultMaxRequestsPerSecond is the budget of the namespaces not selected by any policy, zero disables it.
+	DefaultMaxRequestsPerSecond int64 `yaml:"defaultMaxRequestsPerSecond"`
+}
+
+// RateLimitBudgetPolicy sets the budget of the namespaces selected by the namespace selector, the first matching
+// policy is applied. A single namespace is selected by the "kubernetes.io/metadata.name" label.
+// Note that the label keys are lower-cased when the config is read.
+type RateLimitBudgetPolicy struct {
+	NamespaceSelector    metav1.LabelSelector `yaml:"namespaceSelector"`
+	MaxRequestsPerSecond int64                `yaml:"maxRequestsPerSecond"`
+}
+
+// LocalRateLimit configures the rule validating the local rate limit policies of the virtualhost and routes.
+type LocalRateLimit struct {
+	Enabled bool `yaml:"enabled"`
+	// Mode is one of "warn" or "enforce", defaults to "enforce".
+	Mode     string                 `yaml:"mode"`
+	Policies []LocalRateLimitPolicy `yaml:"policies"`
+	// MaxBurstRatio is the maximum burst in multiples of the requests per unit, defaults to 10.
+	MaxBurstRatio int64 `yaml:"maxBurstRatio"`
+}
+
+// LocalRateLimitPolicy configures the ceilings applied on the HTTPProxy objects of an ingress class.
+// A zero value disables the corresponding ceiling.
+type LocalRateLimitPolicy struct {
+	IngressClassName     string `yaml:"ingressClassName"`
+	MaxRequestsPerSecond int64  `yaml:"maxRequestsPerSecond"`
+	MaxBurst             int64  `yaml:"maxBurst"`
+}
+
+// RateLimitKeyOwnership configures the rule validating the global rate limit keys are not used by other objects.
+type RateLimitKeyOwnership struct {
+	Enabled bool `yaml:"enabled"`
+	// Mode is one of "warn" or "enforce", defaults to "enforce".
+	Mode string `yaml:"mode"`
 }
 
 type Webhook struct {
 	Port        int    `yaml:"port"`
 	TLSCertFile string `yaml:"tlsCertFile"`
 	TLSKeyFile  string `yaml:"tlsKeyFile"`
+	Debug       Debug  `yaml:"debug"`
+}
+
+// Debug configures the /debug endpoints of the webhook server, which require the bearer token read from TokenFile.
+type Debug struct {
+	Enabled   bool   `yaml:"enabled"`
+	TokenFile string `yaml:"tokenFile"`
 }
 
 func GetConfig() Config {
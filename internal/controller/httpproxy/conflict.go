@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+const (
+	fqdnConflictReason         = "FqdnConflict"
+	fqdnConflictResolvedReason = "FqdnConflictResolved"
+)
+
+// claimFqdn persists the FQDN cache entry of the owner. If the entry is already persisted by another object, i.e.
+// the FQDN uniqueness is compromised, the owner is recorded as another claimant instead of overwriting the entry.
+func (re *ReconcilerExtended) claimFqdn(ctx context.Context, logger logr.Logger, cacheKey string, owner types.NamespacedName) {
+	current, found := re.cache.Get(cacheKey)
+	isKeyPersisted := re.cache.IsKeyPersisted(cacheKey)
+	persisted := found && isKeyPersisted != nil && *isKeyPersisted
+
+	// The entry is already persisted for the owner, e.g. the create events replayed after a restart with a snapshot.
+	// Overwriting it would drop the other claimants of a conflicted FQDN.
+	if persisted && *current == owner {
+		return
+	}
+
+	if !persisted {
+		// Add the entry to the cache with persistence.
+		re.cache.Set(cacheKey, &owner, 0)
+
+		return
+	}
+
+	claimants, found := re.cache.AddClaimant(cacheKey, owner)
+	if !found {
+		// The entry is released meanwhile.
+		re.cache.Set(cacheKey, &owner, 0)
+
+		return
+	}
+
+	err := errors.New("fqdn is used in multiple httpproxies")
+
+	logger.Error(err, "fqdn uniqueness is compromised", "entry", cacheKey, "claimants", joinClaimants(claimants))
+
+	observeFqdnClaimants(cacheKey, claimants)
+	re.recordFqdnConflict(ctx, logger, cacheKey, claimants)
+}
+
+// releaseFqdn releases the FQDN cache entry of the owner. The entry is kept while another claimant holds it, otherwise
+// it's quarantined for the namespace of the owner if enabled.
+func (re *ReconcilerExtended) releaseFqdn(ctx context.Context, logger logr.Logger, cacheKey string, owner types.NamespacedName) {
+	entry, found := re.cache.GetEntry(cacheKey)
+	if !found {
+		return
+	}
+
+	// The quarantine is already made, e.g. by another replica sharing the cache handling the same event. Releasing it
+	// would let any namespace take the FQDN over.
+	if entry.Quarantined && entry.Owner == owner {
+		return
+	}
+
+	if re.quarantine > 0 && entry.Owner == owner && len(entry.Claimants) == 0 {
+		re.quarantineFqdn(logger, cacheKey, owner)
+
+		return
+	}
+
+	claimants := re.cache.Release(cacheKey, owner)
+
+	observeFqdnClaimants(cacheKey, claimants)
+
+	if len(entry.Claimants) > 0 && len(claimants) == 1 {
+		logger.Info("fqdn conflict is resolved", "entry", cacheKey, "owner", claimants[0].String())
+
+		re.recordEvent(ctx, logger, claimants[0], corev1.EventTypeNormal, fqdnConflictResolvedReason,
+			fmt.Sprintf("fqdn %s is no longer claimed by other httpproxy objects", cacheKey))
+	}
+}
+
+// recordFqdnConflict records a warning Event on all the claimants of the conflicted FQDN.
+func (re *ReconcilerExtended) recordFqdnConflict(ctx context.Context, logger logr.Logger, cacheKey string, claimants []types.NamespacedName) {
+	message := fmt.Sprintf("fqdn %s is claimed by multiple httpproxy objects: %s", cacheKey, joinClaimants(claimants))
+
+	for _, claimant := range claimants {
+		re.recordEvent(ctx, logger, claimant, corev1.EventTypeWarning, fqdnConflictReason, message)
+	}
+}
+
+func (re *ReconcilerExtended) recordEvent(ctx context.Context, logger logr.Logger, name types.NamespacedName, eventType, reason, message string) {
+	httpproxy := &contourv1.HTTPProxy{}
+
+	if err := re.Client.Get(ctx, name, httpproxy); err != nil {
+		logger.Error(err, "failed to get the httpproxy object to record the event", "httpproxy", name.String(), "reason", reason)
+
+		return
+	}
+
+	re.recorder.Event(httpproxy, eventType, reason, message)
+}
+
+// observeFqdnClaimants exports the number of claimants of the FQDN entry while it's conflicted.
+func observeFqdnClaimants(cacheKey string, claimants []types.NamespacedName) {
+	if len(claimants) > 1 {
+		fqdnClaimants.WithLabelValues(cacheKey).Set(float64(len(claimants)))
+
+		return
+	}
+
+	fqdnClaimants.DeleteLabelValues(cacheKey)
+}
+
+func joinClaimants(claimants []types.NamespacedName) string {
+	names := make([]string, 0, len(claimants))
+
+	for _, claimant := range claimants {
+		names = append(names, claimant.String())
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFqdnConflict(t *testing.T) {
+	utils.SetValidIngressClassNames([]string{"private"})
+
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(contourv1.AddToScheme(testScheme))
+
+	newHttpproxy := func(name string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: name},
+			Spec: contourv1.HTTPProxySpec{
+				IngressClassName: "private",
+				VirtualHost:      &contourv1.VirtualHost{Fqdn: "echo.example.com"},
+			},
+		}
+	}
+
+	echo := newHttpproxy("echo")
+	other := newHttpproxy("other")
+
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(echo, other).Build()
+
+	testCache := cache.NewCache(time.Minute)
+	defer func() { testCache.CleanUpStopChan <- true }()
+
+	recorder := record.NewFakeRecorder(10)
+
+	re := &ReconcilerExtended{cache: testCache, Client: fakeClient, recorder: recorder}
+	ctx := context.Background()
+	cacheKey := "private/echo.example.com"
+
+	re.httpproxyEventHandler(ctx, echo, nil, createEvent)
+	re.httpproxyEventHandler(ctx, other, nil, createEvent)
+
+	// Both claimants are recorded and notified.
+	entry, found := testCache.GetEntry(cacheKey)
+	assert.True(t, found)
+	assert.Equal(t, []types.NamespacedName{{Namespace: "test", Name: "echo"}, {Namespace: "test", Name: "other"}}, entry.AllClaimants())
+	assert.Equal(t, float64(2), testutil.ToFloat64(fqdnClaimants.WithLabelValues(cacheKey)))
+	assert.Len(t, recorder.Events, 2)
+
+	for i := 0; i < 2; i++ {
+		assert.Contains(t, <-recorder.Events, fqdnConflictReason)
+	}
+
+	// Replaying the create event of the owner, e.g. after a restart with a snapshot, keeps the other claimants.
+	re.httpproxyEventHandler(ctx, echo, nil, createEvent)
+
+	entry, _ = testCache.GetEntry(cacheKey)
+	assert.Equal(t, []types.NamespacedName{{Namespace: "test", Name: "echo"}, {Namespace: "test", Name: "other"}}, entry.AllClaimants())
+
+	// The resync keeps the conflict and records the Events again.
+	corrections, err := re.ResyncCache(ctx)
+
+	assert.Nil(t, err)
+	assert.Zero(t, corrections)
+	assert.Len(t, recorder.Events, 2)
+
+	for i := 0; i < 2; i++ {
+		<-recorder.Events
+	}
+
+	// Deleting the owner does not free the FQDN.
+	re.httpproxyEventHandler(ctx, nil, echo, deleteEvent)
+
+	owner, found := testCache.Get(cacheKey)
+	assert.True(t, found)
+	assert.Equal(t, types.NamespacedName{Namespace: "test", Name: "other"}, *owner)
+	assert.Equal(t, 0, testutil.CollectAndCount(fqdnClaimants))
+	assert.Contains(t, <-recorder.Events, fqdnConflictResolvedReason)
+
+	re.httpproxyEventHandler(ctx, nil, other, deleteEvent)
+
+	assert.False(t, testCache.KeyExists(cacheKey))
+}
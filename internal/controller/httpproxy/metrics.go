@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// cacheCorrections counts the cache entries repaired by the resync, labeled by the correction, either "deleted"
+	// for stale entries or "added" for missing ones.
+	cacheCorrections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "contour_admission_webhook_cache_corrections_total",
+			Help: "Number of cache entries repaired by the resync with the HTTPProxy objects",
+		},
+		[]string{"correction"},
+	)
+
+	// fqdnClaimants is the number of HTTPProxy objects claiming each conflicted FQDN entry, labeled by the
+	// ingressClassName/FQDN key. The series is removed once the conflict is resolved.
+	fqdnClaimants = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "contour_admission_webhook_fqdn_claimants",
+			Help: "Number of HTTPProxy objects claiming a conflicted ingressClassName/FQDN",
+		},
+		[]string{"key"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(cacheCorrections, fqdnClaimants)
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// quarantineFqdn replaces the FQDN cache entry with a quarantine, so only the namespace of the owner can reclaim it
+// until it expires.
+func (re *ReconcilerExtended) quarantineFqdn(logger logr.Logger, cacheKey string, owner types.NamespacedName) {
+	expiresAt := time.Now().Add(re.quarantine)
+
+	re.cache.Quarantine(cacheKey, owner, expiresAt.Unix())
+
+	logger.Info("released fqdn is quarantined", "entry", cacheKey, "namespace", owner.Namespace, "until", expiresAt.Format(time.RFC3339))
+}
+
+// quarantineReleasedFqdn quarantines the FQDN released by the HTTPProxy object in the stateless mode, in which the
+// FQDNs are not cached otherwise. The old object is nil on CREATE events and the new object is nil on DELETE events.
+func (re *ReconcilerExtended) quarantineReleasedFqdn(logger logr.Logger, newHttpproxy, oldHttpproxy *contourv1.HTTPProxy) {
+	if re.quarantine <= 0 || oldHttpproxy == nil || oldHttpproxy.Spec.VirtualHost == nil {
+		return
+	}
+
+	oldIngressClassName := utils.GetIngressClassName(oldHttpproxy)
+
+	if !utils.ValidateIngressClassName(oldIngressClassName) {
+		return
+	}
+
+	oldCacheKey := utils.GenerateCacheKey(oldIngressClassName, oldHttpproxy.Spec.VirtualHost.Fqdn)
+
+	if newHttpproxy != nil && newHttpproxy.Spec.VirtualHost != nil &&
+		utils.GenerateCacheKey(utils.GetIngressClassName(newHttpproxy), newHttpproxy.Spec.VirtualHost.Fqdn) == oldCacheKey {
+		return
+	}
+
+	re.quarantineFqdn(logger, oldCacheKey, types.NamespacedName{Namespace: oldHttpproxy.GetNamespace(), Name: oldHttpproxy.GetName()})
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestFqdnQuarantine(t *testing.T) {
+	utils.SetValidIngressClassNames([]string{"private"})
+
+	newHttpproxy := func(name string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: name},
+			Spec: contourv1.HTTPProxySpec{
+				IngressClassName: "private",
+				VirtualHost:      &contourv1.VirtualHost{Fqdn: "echo.example.com"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		stateless bool
+	}{
+		{name: "Should quarantine a released fqdn"},
+		{name: "Should quarantine a released fqdn in the stateless mode", stateless: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testCache := cache.NewCache(time.Minute)
+			defer func() { testCache.CleanUpStopChan <- true }()
+
+			re := &ReconcilerExtended{cache: testCache, stateless: tt.stateless, quarantine: time.Minute}
+			ctx := context.Background()
+			cacheKey := "private/echo.example.com"
+
+			re.httpproxyEventHandler(ctx, newHttpproxy("echo"), nil, createEvent)
+			re.httpproxyEventHandler(ctx, nil, newHttpproxy("echo"), deleteEvent)
+
+			entry, found := testCache.GetEntry(cacheKey)
+			assert.True(t, found)
+			assert.True(t, entry.Quarantined)
+			assert.Equal(t, types.NamespacedName{Namespace: "test", Name: "echo"}, entry.Owner)
+			assert.InDelta(t, time.Now().Add(time.Minute).Unix(), entry.ExpiresAt, 2)
+
+			if tt.stateless {
+				return
+			}
+
+			// The object recreated in the same namespace takes the entry over.
+			re.httpproxyEventHandler(ctx, newHttpproxy("echo-v2"), nil, createEvent)
+
+			entry, _ = testCache.GetEntry(cacheKey)
+			assert.False(t, entry.Quarantined)
+			assert.Equal(t, "echo-v2", entry.Owner.Name)
+			assert.True(t, *testCache.IsKeyPersisted(cacheKey))
+		})
+	}
+}
+
+func TestReleaseFqdnTwice(t *testing.T) {
+	testCache := cache.NewCache(time.Minute)
+	defer func() { testCache.CleanUpStopChan <- true }()
+
+	re := &ReconcilerExtended{cache: testCache, quarantine: time.Minute}
+	ctx := context.Background()
+	cacheKey := "private/echo.example.com"
+	owner := types.NamespacedName{Namespace: "test", Name: "echo"}
+
+	testCache.Set(cacheKey, &owner, 0)
+
+	// The replicas sharing the cache all handle the same DELETE event.
+	re.releaseFqdn(ctx, logr.Discard(), cacheKey, owner)
+	re.releaseFqdn(ctx, logr.Discard(), cacheKey, owner)
+
+	entry, found := testCache.GetEntry(cacheKey)
+	assert.True(t, found)
+	assert.True(t, entry.Quarantined)
+	assert.Equal(t, owner, entry.Owner)
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/go-logr/logr"
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// indexRateLimitKeys maintains the cache entries of the global rate limit keys owned by the HTTPProxy object.
+// The old object is nil on CREATE events and the new object is nil on DELETE events.
+func (re *ReconcilerExtended) indexRateLimitKeys(logger logr.Logger, newHttpproxy, oldHttpproxy *contourv1.HTTPProxy) {
+	var owner types.NamespacedName
+
+	if newHttpproxy != nil {
+		owner = types.NamespacedName{Namespace: newHttpproxy.GetNamespace(), Name: newHttpproxy.GetName()}
+	} else {
+		owner = types.NamespacedName{Namespace: oldHttpproxy.GetNamespace(), Name: oldHttpproxy.GetName()}
+	}
+
+	newCacheKeys := getRateLimitCacheKeys(newHttpproxy)
+
+	for _, cacheKey := range getRateLimitCacheKeys(oldHttpproxy) {
+		if slices.Contains(newCacheKeys, cacheKey) {
+			continue
+		}
+
+		// Only the entries owned by the object are deleted, the key may be reused by another object.
+		if ownerObj, found := re.cache.Get(cacheKey); found && *ownerObj == owner {
+			re.cache.Delete(cacheKey)
+		}
+	}
+
+	for _, cacheKey := range newCacheKeys {
+		ownerObj, found := re.cache.Get(cacheKey)
+		if found && *ownerObj == owner {
+			// Persist the entry reserved by the webhook.
+			re.cache.Set(cacheKey, &owner, 0)
+
+			continue
+		}
+
+		if isKeyPersisted := re.cache.IsKeyPersisted(cacheKey); isKeyPersisted != nil && *isKeyPersisted {
+			err := fmt.Errorf("rate limit key '%s' is used in multiple httpproxies", cacheKey)
+
+			logger.Error(err, "rate limit key uniqueness is compromised", "owner", ownerObj.String(), "httpproxy", owner.String())
+
+			continue
+		}
+
+		// Add the entry to the cache with persistence.
+		re.cache.Set(cacheKey, &owner, 0)
+	}
+}
+
+// getRateLimitCacheKeys returns the cache keys of the global rate limit keys of the HTTPProxy object.
+func getRateLimitCacheKeys(httpproxy *contourv1.HTTPProxy) []string {
+	if httpproxy == nil {
+		return nil
+	}
+
+	ingressClassName := utils.GetIngressClassName(httpproxy)
+
+	if !utils.ValidateIngressClassName(ingressClassName) {
+		return nil
+	}
+
+	cacheKeys := make([]string, 0)
+
+	for _, key := range utils.GetRateLimitKeys(httpproxy) {
+		cacheKeys = append(cacheKeys, utils.GenerateRateLimitCacheKey(ingressClassName, key))
+	}
+
+	return cacheKeys
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ResyncCache lists all the HTTPProxy objects, computes the expected persisted cache entries and repairs the
+// differences. The entries reserved by the webhook are left to expire. It returns the number of corrected entries.
+func (re *ReconcilerExtended) ResyncCache(ctx context.Context) (int, error) {
+	logger := log.FromContext(ctx).WithName("resync")
+
+	// The entries are read before listing the objects. The event handler persists an entry after the object reaches
+	// the informer, so an entry persisted in between belongs to a listed object or is not read, rather than deleted
+	// as stale.
+	entries := re.cache.Entries()
+
+	httpproxies := &contourv1.HTTPProxyList{}
+
+	if err := re.Client.List(ctx, httpproxies); err != nil {
+		return 0, fmt.Errorf("failed to list the httpproxy objects: %w", err)
+	}
+
+	expected := expectedCacheEntries(re.cache, httpproxies.Items, !re.stateless)
+	corrections := 0
+
+	for _, entry := range entries {
+		if entry.ExpiresAt > 0 {
+			continue
+		}
+
+		claimants, found := expected[entry.Key]
+		if !found {
+			re.cache.Delete(entry.Key)
+			observeFqdnClaimants(entry.Key, nil)
+
+			logger.Info("stale cache entry deleted", "entry", entry.Key, "owner", entry.Owner.String())
+			cacheCorrections.WithLabelValues("deleted").Inc()
+
+			corrections++
+
+			continue
+		}
+
+		for _, claimant := range entry.Claimants {
+			if slices.Contains(claimants, claimant) {
+				continue
+			}
+
+			re.cache.Release(entry.Key, claimant)
+
+			logger.Info("stale cache entry claimant released", "entry", entry.Key, "claimant", claimant.String())
+			cacheCorrections.WithLabelValues("deleted").Inc()
+
+			corrections++
+		}
+	}
+
+	for key, claimants := range expected {
+		owner := claimants[0]
+
+		current, found := re.cache.GetEntry(key)
+		if !found || current.Owner != owner || current.ExpiresAt > 0 {
+			// Add the entry to the cache with persistence.
+			re.cache.Set(key, &owner, 0)
+
+			logger.Info("missing cache entry added", "entry", key, "owner", owner.String())
+			cacheCorrections.WithLabelValues("added").Inc()
+
+			corrections++
+
+			current = &cache.Entry{Key: key, Owner: owner}
+		}
+
+		for _, claimant := range claimants[1:] {
+			if slices.Contains(current.Claimants, claimant) {
+				continue
+			}
+
+			re.cache.AddClaimant(key, claimant)
+
+			logger.Info("missing cache entry claimant added", "entry", key, "claimant", claimant.String())
+			cacheCorrections.WithLabelValues("added").Inc()
+
+			corrections++
+		}
+
+		observeFqdnClaimants(key, claimants)
+
+		// The Events are recorded again, so the conflict stays visible until it's resolved.
+		if len(claimants) > 1 {
+			re.recordFqdnConflict(ctx, logger, key, claimants)
+		}
+	}
+
+	return corrections, nil
+}
+
+// StartResync calls ResyncCache every interval until the context is done. The informers must be synced beforehand.
+// It blocks until the context is done, so it can be run by the manager.
+func (re *ReconcilerExtended) StartResync(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid cache resync interval %s", interval)
+	}
+
+	logger := log.FromContext(ctx).WithName("resync")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			corrections, err := re.ResyncCache(ctx)
+			if err != nil {
+				logger.Error(err, "failed to resync the cache")
+
+				continue
+			}
+
+			if corrections > 0 {
+				logger.Info("cache drift is repaired", "corrections", corrections)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// expectedCacheEntries returns the claimants of the persisted cache entries derived from the HTTPProxy objects, the
+// FQDN entries are only included if includeFqdns is set. If a key is claimed by multiple objects, the owner in the
+// cache is kept first. Only the FQDN entries record the other claimants.
+func expectedCacheEntries(c cache.Cache, httpproxies []contourv1.HTTPProxy, includeFqdns bool) map[string][]types.NamespacedName {
+	expected := make(map[string][]types.NamespacedName)
+
+	for i := range httpproxies {
+		httpproxy := &httpproxies[i]
+		owner := types.NamespacedName{Namespace: httpproxy.GetNamespace(), Name: httpproxy.GetName()}
+
+		for _, cacheKey := range getRateLimitCacheKeys(httpproxy) {
+			appendExpectedClaimant(expected, c, cacheKey, owner, false)
+		}
+
+		if includeFqdns && httpproxy.Spec.VirtualHost != nil {
+			ingressClassName := utils.GetIngressClassName(httpproxy)
+
+			if utils.ValidateIngressClassName(ingressClassName) {
+				appendExpectedClaimant(expected, c, utils.GenerateCacheKey(ingressClassName, httpproxy.Spec.VirtualHost.Fqdn), owner, true)
+			}
+		}
+	}
+
+	return expected
+}
+
+// appendExpectedClaimant adds the owner to the expected claimants of the key, keeping the owner in the cache first.
+// Unless multiple is set, only the first claimant is kept.
+func appendExpectedClaimant(expected map[string][]types.NamespacedName, c cache.Cache, cacheKey string, owner types.NamespacedName, multiple bool) {
+	claimants, found := expected[cacheKey]
+	if !found {
+		expected[cacheKey] = []types.NamespacedName{owner}
+
+		return
+	}
+
+	if cached, found := c.Get(cacheKey); found && *cached == owner {
+		claimants = append([]types.NamespacedName{owner}, claimants...)
+	} else {
+		claimants = append(claimants, owner)
+	}
+
+	if !multiple {
+		claimants = claimants[:1]
+	}
+
+	expected[cacheKey] = claimants
+}
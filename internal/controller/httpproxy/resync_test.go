@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestResyncCache(t *testing.T) {
+	utils.SetValidIngressClassNames([]string{"private"})
+
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(contourv1.AddToScheme(testScheme))
+
+	newHttpproxy := func(name, fqdn string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: name},
+			Spec: contourv1.HTTPProxySpec{
+				IngressClassName: "private",
+				VirtualHost:      &contourv1.VirtualHost{Fqdn: fqdn},
+			},
+		}
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithObjects(newHttpproxy("echo", "echo.example.com"), newHttpproxy("missed", "missed.example.com")).
+		Build()
+
+	testCache := cache.NewCache(time.Minute)
+	defer func() { testCache.CleanUpStopChan <- true }()
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+	deleted := types.NamespacedName{Namespace: "test", Name: "deleted"}
+	reserved := types.NamespacedName{Namespace: "test", Name: "reserved"}
+
+	testCache.Set("private/echo.example.com", &echo, 0)
+	testCache.Set("private/deleted.example.com", &deleted, 0)
+	testCache.Set("private/reserved.example.com", &reserved, time.Now().Add(time.Minute).Unix())
+
+	re := &ReconcilerExtended{cache: testCache, Client: fakeClient}
+
+	corrections, err := re.ResyncCache(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, corrections)
+	assert.Equal(t, float64(1), testutil.ToFloat64(cacheCorrections.WithLabelValues("deleted")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(cacheCorrections.WithLabelValues("added")))
+	assert.False(t, testCache.KeyExists("private/deleted.example.com"))
+	assert.True(t, testCache.KeyExists("private/missed.example.com"))
+	assert.True(t, testCache.KeyExists("private/reserved.example.com"))
+
+	corrections, err = re.ResyncCache(context.Background())
+
+	assert.Nil(t, err)
+	assert.Zero(t, corrections)
+}
+
+func TestResyncCacheKeepsEntriesPersistedDuringList(t *testing.T) {
+	utils.SetValidIngressClassNames([]string{"private"})
+
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(contourv1.AddToScheme(testScheme))
+
+	testCache := cache.NewCache(time.Minute)
+	defer func() { testCache.CleanUpStopChan <- true }()
+
+	created := types.NamespacedName{Namespace: "test", Name: "created"}
+
+	// The event handler persists the entry of an object created after the list snapshot is taken.
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				err := c.List(ctx, list, opts...)
+
+				testCache.Set("private/created.example.com", &created, 0)
+
+				return err
+			},
+		}).
+		Build()
+
+	re := &ReconcilerExtended{cache: testCache, Client: fakeClient}
+
+	_, err := re.ResyncCache(context.Background())
+
+	assert.Nil(t, err)
+	assert.True(t, testCache.KeyExists("private/created.example.com"))
+}
+
+func TestResyncCacheStateless(t *testing.T) {
+	utils.SetValidIngressClassNames([]string{"private"})
+
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(contourv1.AddToScheme(testScheme))
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithObjects(&contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "echo"},
+			Spec: contourv1.HTTPProxySpec{
+				IngressClassName: "private",
+				VirtualHost:      &contourv1.VirtualHost{Fqdn: "echo.example.com"},
+			},
+		}).
+		Build()
+
+	testCache := cache.NewCache(time.Minute)
+	defer func() { testCache.CleanUpStopChan <- true }()
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+
+	// The FQDN entries persisted before switching to the stateless mode are deleted.
+	testCache.Set("private/echo.example.com", &echo, 0)
+	testCache.Set("private/reserved.example.com", &echo, time.Now().Add(time.Minute).Unix())
+
+	re := &ReconcilerExtended{cache: testCache, Client: fakeClient, stateless: true}
+
+	corrections, err := re.ResyncCache(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, corrections)
+	assert.False(t, testCache.KeyExists("private/echo.example.com"))
+	assert.True(t, testCache.KeyExists("private/reserved.example.com"))
+}
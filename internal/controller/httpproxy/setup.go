@@ -2,11 +2,11 @@ package controller
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"time"
 
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
 	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/workqueue"
@@ -70,6 +70,21 @@ func (re *ReconcilerExtended) httpproxyEventHandler(ctx context.Context, objNew
 
 	reqs := make([]ctrl.Request, 1)
 
+	// The rate limit keys are indexed regardless of the virtualhost, as routes of included objects can be rate limited.
+	re.indexRateLimitKeys(logger, newHttpproxy, oldHttpproxy)
+
+	// In the stateless mode the FQDN owners are looked up via the field index, so only the quarantines are cached.
+	if re.stateless {
+		re.quarantineReleasedFqdn(logger, newHttpproxy, oldHttpproxy)
+
+		httpproxy := newHttpproxy
+		if httpproxy == nil {
+			httpproxy = oldHttpproxy
+		}
+
+		return append(reqs, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: httpproxy.GetNamespace(), Name: httpproxy.GetName()}})
+	}
+
 	switch et {
 	case createEvent:
 		reqs = append(reqs, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: newHttpproxy.GetNamespace(), Name: newHttpproxy.GetName()}})
@@ -92,25 +107,7 @@ func (re *ReconcilerExtended) httpproxyEventHandler(ctx context.Context, objNew
 
 		cacheKey := utils.GenerateCacheKey(ingressClassName, fqdn)
 
-		if re.cache.KeyExists(cacheKey) {
-			isKeyPersisted := re.cache.IsKeyPersisted(cacheKey)
-			if isKeyPersisted != nil && *isKeyPersisted {
-				errMsg := fmt.Sprintf("fqdn '%s' is used in multiple httpproxies",
-					fqdn)
-
-				err := errors.New(errMsg)
-
-				logger.Error(err, "fqdn uniqueness is compromised")
-
-				break
-			}
-		}
-
-		// Add the entry to the cache with persistence.
-		re.cache.Set(cacheKey,
-			&types.NamespacedName{Namespace: newHttpproxy.GetNamespace(), Name: newHttpproxy.GetName()},
-			0,
-		)
+		re.claimFqdn(ctx, logger, cacheKey, types.NamespacedName{Namespace: newHttpproxy.GetNamespace(), Name: newHttpproxy.GetName()})
 
 	case updateEvent:
 		reqs = append(reqs, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: newHttpproxy.GetNamespace(), Name: newHttpproxy.GetName()}})
@@ -128,7 +125,7 @@ func (re *ReconcilerExtended) httpproxyEventHandler(ctx context.Context, objNew
 
 			cacheKey := utils.GenerateCacheKey(oldIngressClassName, oldFqdn)
 
-			re.cache.Delete(cacheKey)
+			re.releaseFqdn(ctx, logger, cacheKey, types.NamespacedName{Namespace: oldHttpproxy.GetNamespace(), Name: oldHttpproxy.GetName()})
 
 			break
 		}
@@ -142,11 +139,7 @@ func (re *ReconcilerExtended) httpproxyEventHandler(ctx context.Context, objNew
 
 			cacheKey := utils.GenerateCacheKey(newIngressClassName, newFqdn)
 
-			// Add the entry to the cache with persistence.
-			re.cache.Set(cacheKey,
-				&types.NamespacedName{Namespace: newHttpproxy.GetNamespace(), Name: newHttpproxy.GetName()},
-				0,
-			)
+			re.claimFqdn(ctx, logger, cacheKey, types.NamespacedName{Namespace: newHttpproxy.GetNamespace(), Name: newHttpproxy.GetName()})
 
 			break
 		}
@@ -160,12 +153,8 @@ func (re *ReconcilerExtended) httpproxyEventHandler(ctx context.Context, objNew
 			newCacheKey := utils.GenerateCacheKey(newIngressClassName, newFqdn)
 			oldCacheKey := utils.GenerateCacheKey(oldIngressClassName, oldFqdn)
 
-			re.cache.Set(newCacheKey,
-				&types.NamespacedName{Namespace: newHttpproxy.GetNamespace(), Name: newHttpproxy.GetName()},
-				0,
-			)
-
-			re.cache.Delete(oldCacheKey)
+			re.claimFqdn(ctx, logger, newCacheKey, types.NamespacedName{Namespace: newHttpproxy.GetNamespace(), Name: newHttpproxy.GetName()})
+			re.releaseFqdn(ctx, logger, oldCacheKey, types.NamespacedName{Namespace: oldHttpproxy.GetNamespace(), Name: oldHttpproxy.GetName()})
 		}
 
 	case deleteEvent:
@@ -183,22 +172,31 @@ func (re *ReconcilerExtended) httpproxyEventHandler(ctx context.Context, objNew
 
 		cacheKey := utils.GenerateCacheKey(ingressClassName, fqdn)
 
-		// Remove the entry from the cache.
-		re.cache.Delete(cacheKey)
+		// Remove the entry from the cache, unless another object still claims it.
+		re.releaseFqdn(ctx, logger, cacheKey, types.NamespacedName{Namespace: oldHttpproxy.GetNamespace(), Name: oldHttpproxy.GetName()})
 	}
 
 	return reqs
 }
 
 // NewReconcilerExtended instantiate a new ReconcilerExtended struct and returns it.
-func NewReconcilerExtended(mgr manager.Manager, cache *cache.Cache) *ReconcilerExtended {
+func NewReconcilerExtended(mgr manager.Manager, cache cache.Cache) *ReconcilerExtended {
 	return &ReconcilerExtended{
-		cache:  cache,
-		Client: mgr.GetClient(),
-		scheme: mgr.GetScheme(),
+		cache:      cache,
+		Client:     mgr.GetClient(),
+		scheme:     mgr.GetScheme(),
+		stateless:  config.GetConfig().Cache.Stateless,
+		quarantine: time.Duration(config.GetConfig().Cache.QuarantineSecond) * time.Second,
+		recorder:   mgr.GetEventRecorderFor("contour-admission-webhook"),
 	}
 }
 
+// SetupFqdnIndex registers the FQDN field index on the HTTPProxy informer of the manager, which is looked up by the
+// webhook in the stateless mode. It must be called before the manager is started.
+func SetupFqdnIndex(ctx context.Context, mgr manager.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(ctx, &contourv1.HTTPProxy{}, utils.FqdnIndexField, utils.IndexFqdn)
+}
+
 // SetupWithManager sets up the controller with the manager.
 func (re *ReconcilerExtended) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
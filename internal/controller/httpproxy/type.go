@@ -18,11 +18,13 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -44,7 +46,7 @@ type logicFunc func(context.Context) (*ctrl.Result, error)
 type customEventHandlerFunc func(context.Context, client.Object, client.Object, eventType) []ctrl.Request
 
 type ReconcilerExtended struct {
-	cache *cache.Cache
+	cache cache.Cache
 	client.Client
 	// eventType    eventType
 	// httpproxyNew *contourv1.HTTPProxy
@@ -53,6 +55,11 @@ type ReconcilerExtended struct {
 	logger    logr.Logger
 	request   *reconcile.Request
 	scheme    *runtime.Scheme
+	// stateless disables caching the FQDNs, as their owners are looked up via the field index.
+	stateless bool
+	// quarantine is the period a released FQDN is kept for the namespace that released it.
+	quarantine time.Duration
+	recorder   record.EventRecorder
 }
 
 // customEventHandler is a struct that implements the handler.EventHandler interface.
@@ -0,0 +1,176 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingressclass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=projectcontour.io,resources=httpproxy,verbs=get;list;watch
+
+// Reconciler derives the valid ingressClassNames from the IngressClass objects managed by the controller name.
+type Reconciler struct {
+	cache cache.Cache
+	client.Client
+	controllerName string
+	synced         atomic.Bool
+}
+
+// NewReconciler instantiate a new Reconciler struct and returns it.
+func NewReconciler(mgr manager.Manager, cache cache.Cache, controllerName string) *Reconciler {
+	return &Reconciler{
+		cache:          cache,
+		Client:         mgr.GetClient(),
+		controllerName: controllerName,
+	}
+}
+
+// Reconcile lists all the IngressClass objects on any IngressClass event and replaces the valid ingressClassNames.
+// The cache entries of removed ingressClassNames are deleted and the FQDNs and rate limit keys of HTTPProxy objects
+// using the added ingressClassNames are cached.
+func (r *Reconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithName("reconcile")
+
+	ingressClasses := &networkingv1.IngressClassList{}
+
+	if err := r.Client.List(ctx, ingressClasses); err != nil {
+		return ctrl.Result{Requeue: true}, fmt.Errorf("failed to list the ingressclass objects: %w", err)
+	}
+
+	ingressClassNames := make([]string, 0)
+
+	for _, ingressClass := range ingressClasses.Items {
+		if ingressClass.Spec.Controller == r.controllerName {
+			ingressClassNames = append(ingressClassNames, ingressClass.Name)
+		}
+	}
+
+	oldIngressClassNames := utils.GetValidIngressClassNames()
+
+	utils.SetValidIngressClassNames(ingressClassNames)
+	r.synced.Store(true)
+
+	for _, ingressClassName := range difference(oldIngressClassNames, ingressClassNames) {
+		deleted := r.cache.DeleteByPrefix(utils.GenerateCacheKey(ingressClassName, "")) +
+			r.cache.DeleteByPrefix(utils.GenerateRateLimitCacheKey(ingressClassName, ""))
+
+		logger.Info("ingressclass is removed; cache entries deleted", "ingressClassName", ingressClassName, "count", deleted)
+	}
+
+	addedIngressClassNames := difference(ingressClassNames, oldIngressClassNames)
+	if len(addedIngressClassNames) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("ingressclasses are added; caching fqdns and rate limit keys", "ingressClassNames", addedIngressClassNames)
+
+	httpproxies := &contourv1.HTTPProxyList{}
+
+	if err := r.Client.List(ctx, httpproxies); err != nil {
+		return ctrl.Result{Requeue: true}, fmt.Errorf("failed to list the httpproxy objects: %w", err)
+	}
+
+	for i := range httpproxies.Items {
+		httpproxy := &httpproxies.Items[i]
+
+		if utils.IsDeleted(httpproxy) {
+			continue
+		}
+
+		ingressClassName := utils.GetIngressClassName(httpproxy)
+
+		if !slices.Contains(addedIngressClassNames, ingressClassName) {
+			continue
+		}
+
+		for _, key := range utils.GetRateLimitKeys(httpproxy) {
+			r.cache.Set(utils.GenerateRateLimitCacheKey(ingressClassName, key),
+				&types.NamespacedName{Namespace: httpproxy.GetNamespace(), Name: httpproxy.GetName()},
+				0,
+			)
+		}
+
+		if httpproxy.Spec.VirtualHost == nil {
+			continue
+		}
+
+		cacheKey := utils.GenerateCacheKey(ingressClassName, httpproxy.Spec.VirtualHost.Fqdn)
+
+		// Add the entry to the cache with persistence.
+		r.cache.Set(cacheKey,
+			&types.NamespacedName{Namespace: httpproxy.GetNamespace(), Name: httpproxy.GetName()},
+			0,
+		)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// Synced checks whether the valid ingressClassNames are derived from the IngressClass objects at least once. Until
+// then, every ingressClassName is invalid.
+func (r *Reconciler) Synced() bool {
+	return r.synced.Load()
+}
+
+// SetupWithManager sets up the controller with the manager. The ingressClassNames are also derived once the informers
+// are synced, as no event is received if there is no IngressClass object.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		if !mgr.GetCache().WaitForCacheSync(ctx) {
+			return errors.New("failed to wait for the informers to sync")
+		}
+
+		_, err := r.Reconcile(ctx, ctrl.Request{})
+
+		return err
+	})); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&networkingv1.IngressClass{}).
+		Named("ingressclass").
+		Complete(r)
+}
+
+// difference returns the elements of a which are not in b.
+func difference(a, b []string) []string {
+	result := make([]string, 0)
+
+	for _, element := range a {
+		if !slices.Contains(b, element) {
+			result = append(result, element)
+		}
+	}
+
+	return result
+}
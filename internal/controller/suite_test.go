@@ -42,7 +42,7 @@ import (
 // http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
 
 var (
-	cacheStore *cache.Cache
+	cacheStore cache.Cache
 	k8sRestCfg *rest.Config
 	k8sClient  client.Client
 	testEnv    *envtest.Environment
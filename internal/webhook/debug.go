@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+)
+
+// debugCacheHandler serves the cache entries as seen by this replica. A single entry is queried by the key parameter,
+// otherwise the entries are listed, optionally filtered by the prefix parameter.
+type debugCacheHandler struct {
+	cache cache.Cache
+	token string
+}
+
+var _ http.Handler = &debugCacheHandler{}
+
+type debugCacheEntry struct {
+	Key   string `json:"key"`
+	Owner string `json:"owner"`
+	// Persisted is set for the entries of the existing objects, the others are reservations made by the webhook.
+	Persisted  bool   `json:"persisted"`
+	TTLSecond  int64  `json:"ttlSecond,omitempty"`
+	ModifiedAt string `json:"modifiedAt,omitempty"`
+	// Claimants are all the objects claiming a conflicted entry, the owner first.
+	Claimants []string `json:"claimants,omitempty"`
+	// Quarantined is set for the released entries which can only be reclaimed by the namespace of the owner.
+	Quarantined bool `json:"quarantined,omitempty"`
+}
+
+type debugCacheList struct {
+	Entries []debugCacheEntry `json:"entries"`
+}
+
+//nolint:varnamelen
+func (dch *debugCacheHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !dch.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	now := time.Now()
+
+	var response interface{}
+
+	if key := r.URL.Query().Get("key"); key != "" {
+		entry, found := dch.cache.GetEntry(key)
+		if !found {
+			http.Error(w, fmt.Sprintf("cache entry %s not found", key), http.StatusNotFound)
+
+			return
+		}
+
+		response = newDebugCacheEntry(*entry, now)
+	} else {
+		prefix := r.URL.Query().Get("prefix")
+		list := debugCacheList{Entries: make([]debugCacheEntry, 0)}
+
+		for _, entry := range dch.cache.Entries() {
+			if strings.HasPrefix(entry.Key, prefix) {
+				list.Entries = append(list.Entries, newDebugCacheEntry(entry, now))
+			}
+		}
+
+		response = list
+	}
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "error encoding response json", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", echo.MIMEApplicationJSON)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(jsonData); err != nil {
+		logger.Error(err, "error writing the data to the connection as part of an http reply")
+	}
+}
+
+// authorized compares the bearer token of the request with the configured one in constant time.
+func (dch *debugCacheHandler) authorized(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	return found && dch.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(dch.token)) == 1
+}
+
+func newDebugCacheEntry(entry cache.Entry, now time.Time) debugCacheEntry {
+	debugEntry := debugCacheEntry{
+		Key:         entry.Key,
+		Owner:       entry.Owner.String(),
+		Persisted:   entry.ExpiresAt == 0,
+		Quarantined: entry.Quarantined,
+	}
+
+	if entry.ExpiresAt > 0 {
+		debugEntry.TTLSecond = max(entry.ExpiresAt-now.Unix(), 0)
+	}
+
+	if len(entry.Claimants) > 0 {
+		for _, claimant := range entry.AllClaimants() {
+			debugEntry.Claimants = append(debugEntry.Claimants, claimant.String())
+		}
+	}
+
+	if entry.ModifiedAt > 0 {
+		debugEntry.ModifiedAt = time.Unix(entry.ModifiedAt, 0).UTC().Format(time.RFC3339)
+	}
+
+	return debugEntry
+}
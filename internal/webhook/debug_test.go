@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDebugCacheHandler(t *testing.T) {
+	testCache := cache.NewCache(time.Minute)
+	defer func() { testCache.CleanUpStopChan <- true }()
+
+	echo := types.NamespacedName{Namespace: "test", Name: "echo"}
+
+	testCache.Set("private/echo.example.com", &echo, 0)
+	testCache.Set("public/echo.example.com", &echo, time.Now().Add(time.Minute).Unix())
+	testCache.AddClaimant("private/echo.example.com", types.NamespacedName{Namespace: "test", Name: "other"})
+
+	dch := &debugCacheHandler{cache: testCache, token: "secret"}
+
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		code   int
+		keys   []string
+	}{
+		{name: "Should reject a request without a token", target: "/debug/cache", code: http.StatusUnauthorized},
+		{name: "Should reject a request with a wrong token", target: "/debug/cache", token: "wrong", code: http.StatusUnauthorized},
+		{
+			name:   "Should list all the entries",
+			target: "/debug/cache",
+			token:  "secret",
+			code:   http.StatusOK,
+			keys:   []string{"private/echo.example.com", "public/echo.example.com"},
+		},
+		{
+			name:   "Should list the entries matching the prefix",
+			target: "/debug/cache?prefix=public/",
+			token:  "secret",
+			code:   http.StatusOK,
+			keys:   []string{"public/echo.example.com"},
+		},
+		{name: "Should return a single entry", target: "/debug/cache?key=private/echo.example.com", token: "secret", code: http.StatusOK},
+		{name: "Should return not found for a missing entry", target: "/debug/cache?key=private/missing.example.com", token: "secret", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			w := httptest.NewRecorder()
+
+			dch.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.code, w.Code)
+
+			if tt.keys != nil {
+				list := debugCacheList{}
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &list))
+
+				keys := make([]string, 0, len(list.Entries))
+				for _, entry := range list.Entries {
+					keys = append(keys, entry.Key)
+				}
+
+				assert.Equal(t, tt.keys, keys)
+			}
+		})
+	}
+
+	t.Run("Should show the owner, persistence, TTL and modification time of an entry", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/debug/cache?key=public/echo.example.com", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+
+		w := httptest.NewRecorder()
+
+		dch.ServeHTTP(w, r)
+
+		entry := debugCacheEntry{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &entry))
+		assert.Equal(t, "test/echo", entry.Owner)
+		assert.False(t, entry.Persisted)
+		assert.InDelta(t, 60, entry.TTLSecond, 2)
+		assert.NotEmpty(t, entry.ModifiedAt)
+		assert.Empty(t, entry.Claimants)
+	})
+
+	t.Run("Should show all the claimants of a conflicted entry", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/debug/cache?key=private/echo.example.com", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+
+		w := httptest.NewRecorder()
+
+		dch.ServeHTTP(w, r)
+
+		entry := debugCacheEntry{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &entry))
+		assert.True(t, entry.Persisted)
+		assert.Equal(t, []string{"test/echo", "test/other"}, entry.Claimants)
+	})
+}
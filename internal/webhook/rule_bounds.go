@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+type checkBounds struct {
+	next     checker
+	mode     string
+	policies map[string]config.BoundsPolicy // map[ingressClassName]config.BoundsPolicy
+}
+
+//nolint:varnamelen
+func (cb checkBounds) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	// The newIngressClass object should be initialized and populated by previous rules.
+	if cr.newIngressClass == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "ingressClass struct is nil"}
+	}
+
+	policy, found := cb.policies[cr.newIngressClass.name]
+	if !found || isPolicyExempt(cr) {
+		return checkNextWithWarnings(cb.next, cr, nil)
+	}
+
+	violations := make([]string, 0)
+
+	for i, route := range cr.newObj.Spec.Routes {
+		path := fmt.Sprintf("spec.routes[%d]", i)
+
+		if route.TimeoutPolicy != nil {
+			violations = appendDurationViolation(violations, path+".timeoutPolicy.response",
+				route.TimeoutPolicy.Response, policy.MaxResponseTimeoutSecond)
+			violations = appendDurationViolation(violations, path+".timeoutPolicy.idle",
+				route.TimeoutPolicy.Idle, policy.MaxIdleTimeoutSecond)
+			violations = appendDurationViolation(violations, path+".timeoutPolicy.idleConnection",
+				route.TimeoutPolicy.IdleConnection, policy.MaxIdleConnectionTimeoutSecond)
+		}
+
+		if route.RetryPolicy != nil {
+			if policy.MaxRetryCount > 0 && route.RetryPolicy.NumRetries > policy.MaxRetryCount {
+				violations = append(violations, fmt.Sprintf("%s.retryPolicy.count: must not exceed %d", path, policy.MaxRetryCount))
+			}
+
+			violations = appendDurationViolation(violations, path+".retryPolicy.perTryTimeout",
+				route.RetryPolicy.PerTryTimeout, policy.MaxPerTryTimeoutSecond)
+		}
+
+		if route.HealthCheckPolicy != nil {
+			violations = appendHealthCheckViolations(violations, path+".healthCheckPolicy", policy,
+				route.HealthCheckPolicy.IntervalSeconds, route.HealthCheckPolicy.TimeoutSeconds)
+		}
+
+		violations = appendLoadBalancerViolation(violations, path+".loadBalancerPolicy.strategy", policy, route.LoadBalancerPolicy)
+	}
+
+	if tcpProxy := cr.newObj.Spec.TCPProxy; tcpProxy != nil {
+		if tcpProxy.HealthCheckPolicy != nil {
+			violations = appendHealthCheckViolations(violations, "spec.tcpproxy.healthCheckPolicy", policy,
+				tcpProxy.HealthCheckPolicy.IntervalSeconds, tcpProxy.HealthCheckPolicy.TimeoutSeconds)
+		}
+
+		violations = appendLoadBalancerViolation(violations, "spec.tcpproxy.loadBalancerPolicy.strategy", policy, tcpProxy.LoadBalancerPolicy)
+	}
+
+	return reportViolations(cb.mode, cb.next, cr, violations, nil)
+}
+
+func (cb *checkBounds) setNext(c checker) {
+	cb.next = c
+}
+
+// appendDurationViolation appends a violation if the Contour duration exceeds the limit.
+// Contour durations are either Go durations or one of "infinity" and "infinite".
+func appendDurationViolation(violations []string, path, value string, maxSecond int) []string {
+	if value == "" || maxSecond <= 0 {
+		return violations
+	}
+
+	if value == "infinity" || value == "infinite" {
+		return append(violations, fmt.Sprintf("%s: must not be %s, maximum is %ds", path, value, maxSecond))
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return append(violations, fmt.Sprintf("%s: %s is not a valid duration", path, value))
+	}
+
+	if duration > time.Duration(maxSecond)*time.Second {
+		return append(violations, fmt.Sprintf("%s: must not exceed %ds", path, maxSecond))
+	}
+
+	return violations
+}
+
+func appendHealthCheckViolations(violations []string, path string, policy config.BoundsPolicy, intervalSeconds, timeoutSeconds int64) []string {
+	if policy.MinHealthCheckIntervalSecond > 0 && intervalSeconds > 0 && intervalSeconds < policy.MinHealthCheckIntervalSecond {
+		violations = append(violations,
+			fmt.Sprintf("%s.intervalSeconds: must be at least %d", path, policy.MinHealthCheckIntervalSecond))
+	}
+
+	if policy.MaxHealthCheckTimeoutSecond > 0 && timeoutSeconds > policy.MaxHealthCheckTimeoutSecond {
+		violations = append(violations,
+			fmt.Sprintf("%s.timeoutSeconds: must not exceed %d", path, policy.MaxHealthCheckTimeoutSecond))
+	}
+
+	return violations
+}
+
+func appendLoadBalancerViolation(violations []string, path string, policy config.BoundsPolicy, lbPolicy *contourv1.LoadBalancerPolicy) []string {
+	if len(policy.LoadBalancerStrategies) == 0 || lbPolicy == nil || lbPolicy.Strategy == "" {
+		return violations
+	}
+
+	for _, strategy := range policy.LoadBalancerStrategies {
+		if lbPolicy.Strategy == strategy {
+			return violations
+		}
+	}
+
+	return append(violations, fmt.Sprintf("%s: %s is not allowed, must be one of %v", path, lbPolicy.Strategy, policy.LoadBalancerStrategies))
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckBounds(t *testing.T) {
+	policies := map[string]config.BoundsPolicy{
+		"public": {
+			IngressClassName:         "public",
+			MaxResponseTimeoutSecond: 60,
+			MaxRetryCount:            3,
+			LoadBalancerStrategies:   []string{"RoundRobin"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		mode          string
+		route         contourv1.Route
+		allowed       bool
+		warningsCount int
+	}{
+		{
+			name:    "Should allow values within the limits",
+			mode:    config.RuleModeEnforce,
+			route:   contourv1.Route{TimeoutPolicy: &contourv1.TimeoutPolicy{Response: "30s"}, RetryPolicy: &contourv1.RetryPolicy{NumRetries: 3}},
+			allowed: true,
+		},
+		{
+			name:    "Should deny an infinite response timeout in enforce mode",
+			mode:    config.RuleModeEnforce,
+			route:   contourv1.Route{TimeoutPolicy: &contourv1.TimeoutPolicy{Response: "infinity"}},
+			allowed: false,
+		},
+		{
+			name:          "Should warn about a high retry count in warn mode",
+			mode:          config.RuleModeWarn,
+			route:         contourv1.Route{RetryPolicy: &contourv1.RetryPolicy{NumRetries: 10}},
+			allowed:       true,
+			warningsCount: 1,
+		},
+		{
+			name:    "Should deny a load balancer strategy which is not allowed",
+			mode:    config.RuleModeEnforce,
+			route:   contourv1.Route{LoadBalancerPolicy: &contourv1.LoadBalancerPolicy{Strategy: "Cookie"}},
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &checkBounds{mode: tt.mode, policies: policies}
+
+			response, err := checker.check(&checkRequest{
+				newObj:          &contourv1.HTTPProxy{Spec: contourv1.HTTPProxySpec{Routes: []contourv1.Route{tt.route}}},
+				newIngressClass: &ingressClass{name: "public", valid: true},
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+			assert.Len(t, response.Warnings, tt.warningsCount)
+		})
+	}
+
+	exceeding := &contourv1.HTTPProxy{Spec: contourv1.HTTPProxySpec{
+		Routes: []contourv1.Route{{TimeoutPolicy: &contourv1.TimeoutPolicy{Response: "infinity"}}},
+	}}
+
+	t.Run("Should allow an update not changing the spec of an object exceeding the limits in enforce mode", func(t *testing.T) {
+		oldObj := exceeding.DeepCopy()
+		oldObj.Finalizers = []string{"test"}
+
+		response, err := (&checkBounds{mode: config.RuleModeEnforce, policies: policies}).check(&checkRequest{
+			newObj:          exceeding,
+			oldObj:          oldObj,
+			newIngressClass: &ingressClass{name: "public", valid: true},
+			oldIngressClass: &ingressClass{name: "public", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+
+	t.Run("Should allow an update of an object being deleted in enforce mode", func(t *testing.T) {
+		newObj := exceeding.DeepCopy()
+		newObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		response, err := (&checkBounds{mode: config.RuleModeEnforce, policies: policies}).check(&checkRequest{
+			newObj:          newObj,
+			newIngressClass: &ingressClass{name: "public", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+}
@@ -1,14 +1,17 @@
 package webhook
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
 
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type checkFqdnOnCreate struct {
@@ -51,26 +54,8 @@ func (cfoc checkFqdnOnCreate) check(cr *checkRequest) (*admissionv1.AdmissionRes
 	// checking for zero value is not required as it's handled in the Kube API server before sending admission request to the webhook.
 	fqdn := cr.newObj.Spec.VirtualHost.Fqdn
 
-	cacheKey := utils.GenerateCacheKey(cr.newIngressClass.name, fqdn)
-
-	if cr.cache.KeyExists(cacheKey) {
-		ownerObj, _ := cr.cache.Get(cacheKey)
-
-		return &admissionv1.AdmissionResponse{Allowed: false,
-			Result: &metav1.Status{
-				// The http code and message returned to the user
-				Code: http.StatusForbidden,
-				Message: fmt.Sprintf("fqdn is already acquired by another httpproxy object named %s in namespace %s",
-					ownerObj.Name,
-					ownerObj.Namespace),
-			}}, nil
-	}
-
-	if !dryRun {
-		cr.cache.Set(cacheKey,
-			&types.NamespacedName{Namespace: cr.newObj.Namespace, Name: cr.newObj.Name},
-			time.Now().Add(time.Duration(entryTtlSecond)*time.Second).Unix(),
-		)
+	if response, err := reserveFqdn(cr, cr.newIngressClass.name, fqdn, dryRun); response != nil || err != nil {
+		return response, err
 	}
 
 	if cfoc.next != nil {
@@ -130,26 +115,8 @@ func (cfou checkFqdnOnUpdate) check(cr *checkRequest) (*admissionv1.AdmissionRes
 		// checking for zero value is not required as it's handled in the Kube API server before sending admission request to the webhook.
 		newFqdn := cr.newObj.Spec.VirtualHost.Fqdn
 
-		cacheKey := utils.GenerateCacheKey(newIngressClassName, newFqdn)
-
-		if cr.cache.KeyExists(cacheKey) {
-			ownerObj, _ := cr.cache.Get(cacheKey)
-
-			return &admissionv1.AdmissionResponse{Allowed: false,
-				Result: &metav1.Status{
-					// The http code and message returned to the user
-					Code: http.StatusForbidden,
-					Message: fmt.Sprintf("fqdn is already acquired by another httpproxy object named %s in namespace %s",
-						ownerObj.Name,
-						ownerObj.Namespace),
-				}}, nil
-		}
-
-		if !dryRun {
-			cr.cache.Set(cacheKey,
-				&types.NamespacedName{Namespace: cr.newObj.Namespace, Name: cr.newObj.Name},
-				time.Now().Add(time.Duration(entryTtlSecond)*time.Second).Unix(),
-			)
+		if response, err := reserveFqdn(cr, newIngressClassName, newFqdn, dryRun); response != nil || err != nil {
+			return response, err
 		}
 
 		if cfou.next != nil {
@@ -172,26 +139,8 @@ func (cfou checkFqdnOnUpdate) check(cr *checkRequest) (*admissionv1.AdmissionRes
 		return &admissionv1.AdmissionResponse{Allowed: true}, nil
 	}
 
-	newCacheKey := utils.GenerateCacheKey(newIngressClassName, fqdn)
-
-	if cr.cache.KeyExists(newCacheKey) {
-		ownerObj, _ := cr.cache.Get(newCacheKey)
-
-		return &admissionv1.AdmissionResponse{Allowed: false,
-			Result: &metav1.Status{
-				// The http code and message returned to the user
-				Code: http.StatusForbidden,
-				Message: fmt.Sprintf("fqdn is already acquired by another httpproxy object named %s in namespace %s",
-					ownerObj.Name,
-					ownerObj.Namespace),
-			}}, nil
-	}
-
-	if !dryRun {
-		cr.cache.Set(newCacheKey,
-			&types.NamespacedName{Namespace: cr.newObj.Namespace, Name: cr.newObj.Name},
-			time.Now().Add(time.Duration(entryTtlSecond)*time.Second).Unix(),
-		)
+	if response, err := reserveFqdn(cr, newIngressClassName, fqdn, dryRun); response != nil || err != nil {
+		return response, err
 	}
 
 	if cfou.next != nil {
@@ -216,3 +165,113 @@ func (cfod checkFqdnOnDelete) check(cr *checkRequest) (*admissionv1.AdmissionRes
 func (cfod *checkFqdnOnDelete) setNext(c checker) {
 	cfod.next = c
 }
+
+// reserveFqdn reserves the cache key of the FQDN for the HTTPProxy object unless it's a dry-run request. It returns a
+// denying response if the FQDN is already acquired, quarantined or, in the hierarchical mode, reserved as a subdomain
+// for another namespace, otherwise nil. The key is reserved atomically, so the replicas sharing the cache can not
+// acquire the same key concurrently. In the stateless mode, the persisted owners are looked up via the field index and
+// the cache only holds the reservations.
+func reserveFqdn(cr *checkRequest, ingressClassName, fqdn string, dryRun bool) (*admissionv1.AdmissionResponse, *httpErr) {
+	if rulesConfig.FqdnHierarchy.Enabled {
+		if response, err := checkFqdnHierarchy(cr, ingressClassName, fqdn); response != nil || err != nil {
+			return response, err
+		}
+	}
+
+	cacheKey := utils.GenerateCacheKey(ingressClassName, fqdn)
+
+	var ownerObj *types.NamespacedName
+
+	entry, err := cr.cache.LookupEntry(cacheKey)
+	if err != nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: fmt.Sprintf("failed to look up the fqdn: %s", err.Error())}
+	}
+
+	found := entry != nil
+	quarantined := found && entry.Quarantined
+
+	if quarantined && entry.Owner.Namespace != cr.newObj.Namespace {
+		return &admissionv1.AdmissionResponse{Allowed: false,
+			Result: &metav1.Status{
+				// The http code and message returned to the user
+				Code: http.StatusForbidden,
+				Message: fmt.Sprintf("fqdn was released by the httpproxy object named %s in namespace %s and is quarantined until %s; only namespace %s can reclaim it",
+					entry.Owner.Name,
+					entry.Owner.Namespace,
+					time.Unix(entry.ExpiresAt, 0).UTC().Format(time.RFC3339),
+					entry.Owner.Namespace),
+			}}, nil
+	}
+
+	if found && !quarantined {
+		ownerObj = &entry.Owner
+	} else {
+		found = false
+	}
+
+	if !found && statelessFqdnLookup {
+		ownerObj, err = getIndexedFqdnOwner(cr, utils.GenerateFqdnIndexKey(ingressClassName, fqdn))
+		if err != nil {
+			return nil, &httpErr{code: http.StatusInternalServerError,
+				message: fmt.Sprintf("failed to look up the fqdn: %s", err.Error())}
+		}
+
+		found = ownerObj != nil
+	}
+
+	owner := types.NamespacedName{Namespace: cr.newObj.Namespace, Name: cr.newObj.Name}
+	expiresAt := time.Now().Add(time.Duration(entryTtlSecond) * time.Second).Unix()
+
+	switch {
+	case found || dryRun:
+	case quarantined:
+		// The quarantine is replaced by the reservation of the same namespace. Concurrent reclaims of the namespace
+		// are not excluded, as they're tracked as the claimants of the conflicted FQDN.
+		cr.cache.Set(cacheKey, &owner, expiresAt)
+	default:
+		var reserved bool
+
+		var err error
+
+		ownerObj, reserved, err = cr.cache.Reserve(cacheKey, &owner, expiresAt)
+		if err != nil {
+			return nil, &httpErr{code: http.StatusInternalServerError,
+				message: fmt.Sprintf("failed to reserve the fqdn: %s", err.Error())}
+		}
+
+		found = !reserved
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return &admissionv1.AdmissionResponse{Allowed: false,
+		Result: &metav1.Status{
+			// The http code and message returned to the user
+			Code: http.StatusForbidden,
+			Message: fmt.Sprintf("fqdn is already acquired by another httpproxy object named %s in namespace %s",
+				ownerObj.Name,
+				ownerObj.Namespace),
+		}}, nil
+}
+
+// getIndexedFqdnOwner returns another HTTPProxy object having the FQDN index key, or nil if there is none.
+func getIndexedFqdnOwner(cr *checkRequest, indexKey string) (*types.NamespacedName, error) {
+	httpproxies := &contourv1.HTTPProxyList{}
+
+	if err := cr.client.List(context.Background(), httpproxies, client.MatchingFields{utils.FqdnIndexField: indexKey}); err != nil {
+		return nil, err
+	}
+
+	for i := range httpproxies.Items {
+		owner := types.NamespacedName{Namespace: httpproxies.Items[i].Namespace, Name: httpproxies.Items[i].Name}
+
+		if owner.Namespace != cr.newObj.Namespace || owner.Name != cr.newObj.Name {
+			return &owner, nil
+		}
+	}
+
+	return nil, nil
+}
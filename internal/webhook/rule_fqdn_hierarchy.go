@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// checkFqdnHierarchy returns a denying response if the closest claimed parent of the FQDN is owned by another
+// namespace which does not delegate its subdomains to the namespace of the HTTPProxy object, otherwise nil.
+func checkFqdnHierarchy(cr *checkRequest, ingressClassName, fqdn string) (*admissionv1.AdmissionResponse, *httpErr) {
+	for _, parent := range getParentFqdns(fqdn) {
+		owner, quarantined, err := getParentFqdnOwner(cr, ingressClassName, parent)
+		if err != nil {
+			return nil, &httpErr{code: http.StatusInternalServerError,
+				message: fmt.Sprintf("failed to look up the parent fqdn %s: %s", parent, err.Error())}
+		}
+
+		if owner == nil {
+			continue
+		}
+
+		if owner.Namespace == cr.newObj.Namespace {
+			return nil, nil
+		}
+
+		// A quarantined parent has no object to delegate its subdomains.
+		if !quarantined {
+			delegated, err := isSubdomainDelegated(cr, *owner)
+			if err != nil {
+				return nil, &httpErr{code: http.StatusInternalServerError,
+					message: fmt.Sprintf("failed to get the owner of the parent fqdn %s: %s", parent, err.Error())}
+			}
+
+			if delegated {
+				return nil, nil
+			}
+		}
+
+		return &admissionv1.AdmissionResponse{Allowed: false,
+			Result: &metav1.Status{
+				// The http code and message returned to the user
+				Code: http.StatusForbidden,
+				Message: fmt.Sprintf("fqdn is a subdomain of %s owned by the httpproxy object named %s in namespace %s, which does not delegate its subdomains to namespace %s",
+					parent,
+					owner.Name,
+					owner.Namespace,
+					cr.newObj.Namespace),
+			}}, nil
+	}
+
+	return nil, nil
+}
+
+// getParentDomains returns the parent domains of the FQDN from the closest one, excluding the top-level domain.
+// The parent of a wildcard FQDN is the domain it matches the subdomains of.
+func getParentDomains(fqdn string) []string {
+	fqdn = strings.TrimSuffix(strings.ToLower(fqdn), ".")
+	domain, wildcard := strings.CutPrefix(fqdn, "*.")
+	labels := strings.Split(domain, ".")
+
+	parents := make([]string, 0, len(labels))
+
+	if wildcard {
+		parents = append(parents, domain)
+	}
+
+	for i := 1; i < len(labels)-1; i++ {
+		parents = append(parents, strings.Join(labels[i:], "."))
+	}
+
+	return parents
+}
+
+// getParentFqdns returns the FQDNs claiming the subdomains of the FQDN from the closest one. Each parent domain is
+// preceded by its wildcard, which matches the FQDN directly.
+func getParentFqdns(fqdn string) []string {
+	fqdn = strings.TrimSuffix(strings.ToLower(fqdn), ".")
+	parents := make([]string, 0)
+
+	for _, domain := range getParentDomains(fqdn) {
+		if wildcard := "*." + domain; wildcard != fqdn {
+			parents = append(parents, wildcard)
+		}
+
+		parents = append(parents, domain)
+	}
+
+	return parents
+}
+
+// getParentFqdnOwner returns the owner of the parent domain, or nil if it's not claimed. A quarantined parent is owned
+// by the namespace that released it.
+func getParentFqdnOwner(cr *checkRequest, ingressClassName, parent string) (*types.NamespacedName, bool, error) {
+	entry, err := cr.cache.LookupEntry(utils.GenerateCacheKey(ingressClassName, parent))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if entry != nil {
+		return &entry.Owner, entry.Quarantined, nil
+	}
+
+	if !statelessFqdnLookup {
+		return nil, false, nil
+	}
+
+	owner, err := getIndexedFqdnOwner(cr, utils.GenerateFqdnIndexKey(ingressClassName, parent))
+
+	return owner, false, err
+}
+
+// isSubdomainDelegated checks whether the delegation annotation of the owner lists the namespace of the HTTPProxy
+// object. A reservation of an object not persisted yet does not delegate its subdomains.
+func isSubdomainDelegated(cr *checkRequest, owner types.NamespacedName) (bool, error) {
+	annotation := rulesConfig.FqdnHierarchy.DelegationAnnotation
+	if annotation == "" {
+		return false, nil
+	}
+
+	httpproxy := &contourv1.HTTPProxy{}
+
+	if err := cr.client.Get(context.Background(), owner, httpproxy); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	for _, namespace := range strings.Split(httpproxy.Annotations[annotation], ",") {
+		namespace = strings.TrimSpace(namespace)
+
+		if namespace == "*" || namespace == cr.newObj.Namespace {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
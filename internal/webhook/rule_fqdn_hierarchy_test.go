@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckFqdnHierarchy(t *testing.T) {
+	rulesConfig.FqdnHierarchy = config.FqdnHierarchy{Enabled: true, DelegationAnnotation: "snappcloud.io/delegate-subdomains"}
+	defer func() { rulesConfig.FqdnHierarchy = config.FqdnHierarchy{} }()
+
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(contourv1.AddToScheme(testScheme))
+
+	newHttpproxy := func(namespace, name, fqdn, delegation string) *contourv1.HTTPProxy {
+		httpproxy := &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: contourv1.HTTPProxySpec{
+				IngressClassName: "private",
+				VirtualHost:      &contourv1.VirtualHost{Fqdn: fqdn},
+			},
+		}
+
+		if delegation != "" {
+			httpproxy.Annotations = map[string]string{"snappcloud.io/delegate-subdomains": delegation}
+		}
+
+		return httpproxy
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithObjects(
+			newHttpproxy("owner", "root", "example.com", ""),
+			newHttpproxy("owner", "delegating", "delegated.example.com", "test, other"),
+			newHttpproxy("owner", "public", "public.example.com", "*"),
+		).
+		Build()
+
+	tests := []struct {
+		name    string
+		newObj  *contourv1.HTTPProxy
+		allowed bool
+	}{
+		{name: "Should allow an fqdn without a claimed parent", newObj: newHttpproxy("test", "new", "echo.example.org", ""), allowed: true},
+		{name: "Should allow a subdomain of the same namespace", newObj: newHttpproxy("owner", "new", "api.example.com", ""), allowed: true},
+		{name: "Should deny a subdomain of another namespace", newObj: newHttpproxy("test", "new", "api.example.com", ""), allowed: false},
+		{name: "Should deny a nested subdomain of another namespace", newObj: newHttpproxy("test", "new", "v1.api.example.com", ""), allowed: false},
+		{name: "Should deny a wildcard of another namespace", newObj: newHttpproxy("test", "new", "*.example.com", ""), allowed: false},
+		{name: "Should allow a subdomain delegated to the namespace", newObj: newHttpproxy("test", "new", "api.delegated.example.com", ""), allowed: true},
+		{name: "Should deny a subdomain not delegated to the namespace", newObj: newHttpproxy("another", "new", "api.delegated.example.com", ""), allowed: false},
+		{name: "Should allow a subdomain delegated to all the namespaces", newObj: newHttpproxy("another", "new", "api.public.example.com", ""), allowed: true},
+		{name: "Should deny a subdomain of a reservation", newObj: newHttpproxy("test", "new", "api.reserved.example.com", ""), allowed: false},
+		{name: "Should deny a subdomain of a quarantined fqdn", newObj: newHttpproxy("test", "new", "api.quarantined.example.com", ""), allowed: false},
+		{name: "Should deny a subdomain covered by a wildcard of another namespace", newObj: newHttpproxy("test", "new", "api.wildcard.org", ""), allowed: false},
+		{name: "Should allow a subdomain covered by a wildcard of the same namespace", newObj: newHttpproxy("owner", "new", "v1.api.wildcard.org", ""), allowed: true},
+		{name: "Should deny the wildcard itself acquired by another namespace", newObj: newHttpproxy("test", "new", "*.wildcard.org", ""), allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testCache := cache.NewCache(time.Minute)
+			defer func() { testCache.CleanUpStopChan <- true }()
+
+			testCache.Set("private/example.com", &types.NamespacedName{Namespace: "owner", Name: "root"}, 0)
+			testCache.Set("private/delegated.example.com", &types.NamespacedName{Namespace: "owner", Name: "delegating"}, 0)
+			testCache.Set("private/public.example.com", &types.NamespacedName{Namespace: "owner", Name: "public"}, 0)
+			testCache.Set("private/reserved.example.com", &types.NamespacedName{Namespace: "owner", Name: "reserved"},
+				time.Now().Add(time.Minute).Unix())
+			testCache.Set("private/*.wildcard.org", &types.NamespacedName{Namespace: "owner", Name: "wildcard"}, 0)
+			testCache.Quarantine("private/quarantined.example.com", types.NamespacedName{Namespace: "owner", Name: "quarantined"},
+				time.Now().Add(time.Minute).Unix())
+
+			cr := &checkRequest{newObj: tt.newObj, cache: testCache, client: fakeClient}
+
+			response, err := reserveFqdn(cr, "private", tt.newObj.Spec.VirtualHost.Fqdn, false)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response == nil)
+		})
+	}
+}
+
+func TestGetParentDomains(t *testing.T) {
+	assert.Empty(t, getParentDomains("example.com"))
+	assert.Equal(t, []string{"api.example.com", "example.com"}, getParentDomains("v1.api.example.com"))
+	assert.Equal(t, []string{"api.example.com", "example.com"}, getParentDomains("*.api.example.com"))
+	assert.Equal(t, []string{"example.com"}, getParentDomains("API.Example.com."))
+}
+
+func TestGetParentFqdns(t *testing.T) {
+	assert.Equal(t, []string{"*.api.example.com", "api.example.com", "*.example.com", "example.com"}, getParentFqdns("v1.api.example.com"))
+	assert.Equal(t, []string{"api.example.com", "*.example.com", "example.com"}, getParentFqdns("*.api.example.com"))
+}
+
+// unavailableCache is a cache whose backend is unreachable.
+type unavailableCache struct {
+	cache.Cache
+}
+
+func (unavailableCache) LookupEntry(string) (*cache.Entry, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestCheckFqdnHierarchyUnavailableCache(t *testing.T) {
+	rulesConfig.FqdnHierarchy = config.FqdnHierarchy{Enabled: true}
+	defer func() { rulesConfig.FqdnHierarchy = config.FqdnHierarchy{} }()
+
+	testCache := cache.NewCache(time.Minute)
+	defer func() { testCache.CleanUpStopChan <- true }()
+
+	cr := &checkRequest{
+		newObj: &contourv1.HTTPProxy{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "new"}},
+		cache:  unavailableCache{Cache: testCache},
+	}
+
+	// The lookup errors must not be taken as unclaimed parents.
+	response, err := reserveFqdn(cr, "private", "api.example.com", false)
+
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, err.code)
+	assert.False(t, testCache.KeyExists("private/api.example.com"))
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReserveFqdnStateless(t *testing.T) {
+	statelessFqdnLookup = true
+	defer func() { statelessFqdnLookup = false }()
+
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(contourv1.AddToScheme(testScheme))
+
+	newHttpproxy := func(name, fqdn string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: name},
+			Spec: contourv1.HTTPProxySpec{
+				IngressClassName: "private",
+				VirtualHost:      &contourv1.VirtualHost{Fqdn: fqdn},
+			},
+		}
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithObjects(newHttpproxy("owner", "owner.example.com"), newHttpproxy("echo", "echo.example.com")).
+		WithIndex(&contourv1.HTTPProxy{}, utils.FqdnIndexField, utils.IndexFqdn).
+		Build()
+
+	tests := []struct {
+		name     string
+		newObj   *contourv1.HTTPProxy
+		dryRun   bool
+		allowed  bool
+		reserved bool
+	}{
+		{name: "Should allow and reserve an unused fqdn", newObj: newHttpproxy("new", "new.example.com"), allowed: true, reserved: true},
+		{name: "Should not reserve an unused fqdn on dry-run", newObj: newHttpproxy("new", "new.example.com"), dryRun: true, allowed: true},
+		{name: "Should deny an fqdn of another indexed object", newObj: newHttpproxy("new", "owner.example.com"), allowed: false},
+		{name: "Should deny an fqdn of another indexed object on dry-run", newObj: newHttpproxy("new", "owner.example.com"), dryRun: true, allowed: false},
+		{name: "Should allow the fqdn of the object itself", newObj: newHttpproxy("echo", "echo.example.com"), allowed: true, reserved: true},
+		{name: "Should deny an fqdn reserved by another object", newObj: newHttpproxy("new", "reserved.example.com"), allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testCache := cache.NewCache(time.Minute)
+			defer func() { testCache.CleanUpStopChan <- true }()
+
+			testCache.Set("private/reserved.example.com", &types.NamespacedName{Namespace: "test", Name: "reserved"},
+				time.Now().Add(time.Minute).Unix())
+
+			cr := &checkRequest{newObj: tt.newObj, dryRun: &tt.dryRun, cache: testCache, client: fakeClient}
+
+			response, err := reserveFqdn(cr, "private", tt.newObj.Spec.VirtualHost.Fqdn, tt.dryRun)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response == nil)
+
+			owner, found := testCache.Get(utils.GenerateCacheKey("private", tt.newObj.Spec.VirtualHost.Fqdn))
+			assert.Equal(t, tt.reserved, found && owner.Name == tt.newObj.Name)
+		})
+	}
+}
+
+func TestReserveFqdnQuarantine(t *testing.T) {
+	newHttpproxy := func(namespace string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "new"},
+			Spec: contourv1.HTTPProxySpec{
+				IngressClassName: "private",
+				VirtualHost:      &contourv1.VirtualHost{Fqdn: "echo.example.com"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		newObj   *contourv1.HTTPProxy
+		dryRun   bool
+		allowed  bool
+		reserved bool
+	}{
+		{name: "Should deny another namespace", newObj: newHttpproxy("other"), allowed: false},
+		{name: "Should deny another namespace on dry-run", newObj: newHttpproxy("other"), dryRun: true, allowed: false},
+		{name: "Should allow the same namespace and reserve the fqdn", newObj: newHttpproxy("test"), allowed: true, reserved: true},
+		{name: "Should allow the same namespace on dry-run", newObj: newHttpproxy("test"), dryRun: true, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testCache := cache.NewCache(time.Minute)
+			defer func() { testCache.CleanUpStopChan <- true }()
+
+			testCache.Quarantine("private/echo.example.com", types.NamespacedName{Namespace: "test", Name: "echo"},
+				time.Now().Add(time.Minute).Unix())
+
+			cr := &checkRequest{newObj: tt.newObj, dryRun: &tt.dryRun, cache: testCache}
+
+			response, err := reserveFqdn(cr, "private", "echo.example.com", tt.dryRun)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response == nil)
+
+			if !tt.allowed {
+				assert.Contains(t, response.Result.Message, "quarantined until")
+			}
+
+			entry, found := testCache.GetEntry("private/echo.example.com")
+			assert.True(t, found)
+			assert.Equal(t, tt.reserved, !entry.Quarantined && entry.Owner.Name == tt.newObj.Name)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type checkHostRewrite struct {
+	next              checker
+	allowedHosts      []string
+	domainDelegations []config.DomainDelegation
+}
+
+// hostTarget is a host targeted by a Host header rewrite or a redirect along with its field path.
+type hostTarget struct {
+	path string
+	host string
+}
+
+//nolint:varnamelen
+func (chr checkHostRewrite) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	if isPolicyExempt(cr) {
+		if chr.next != nil {
+			return chr.next.check(cr)
+		}
+
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+
+	// The newIngressClass object should be initialized and populated by previous rules.
+	if cr.newIngressClass == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "ingressClass struct is nil"}
+	}
+
+	violations := make([]string, 0)
+
+	for _, target := range getHostTargets(cr.newObj) {
+		host := getHostWithoutPort(target.host)
+
+		permitted, err := chr.isHostPermitted(cr, host)
+		if err != nil {
+			return nil, &httpErr{code: http.StatusInternalServerError,
+				message: fmt.Sprintf("failed to look up the owner of host %s: %s", host, err.Error())}
+		}
+
+		if permitted {
+			continue
+		}
+
+		violations = append(violations, fmt.Sprintf("%s: host %s is not owned by namespace %s", target.path, host, cr.newObj.Namespace))
+	}
+
+	if len(violations) > 0 {
+		return &admissionv1.AdmissionResponse{Allowed: false,
+			Result: &metav1.Status{
+				// http code and message returned to the user
+				Code:    http.StatusForbidden,
+				Message: strings.Join(violations, "; "),
+			}}, nil
+	}
+
+	if chr.next != nil {
+		return chr.next.check(cr)
+	}
+
+	return &admissionv1.AdmissionResponse{Allowed: true}, nil
+}
+
+func (chr *checkHostRewrite) setNext(c checker) {
+	chr.next = c
+}
+
+// isHostPermitted checks whether the namespace of the HTTPProxy object can target the host.
+func (chr checkHostRewrite) isHostPermitted(cr *checkRequest, host string) (bool, error) {
+	if cr.newObj.Spec.VirtualHost != nil && host == cr.newObj.Spec.VirtualHost.Fqdn {
+		return true, nil
+	}
+
+	for _, allowedHost := range chr.allowedHosts {
+		if matchHost(allowedHost, host) {
+			return true, nil
+		}
+	}
+
+	for _, delegation := range chr.domainDelegations {
+		if host != delegation.Domain && !strings.HasSuffix(host, "."+delegation.Domain) {
+			continue
+		}
+
+		for _, namespace := range delegation.Namespaces {
+			if namespace == cr.newObj.Namespace {
+				return true, nil
+			}
+		}
+	}
+
+	// The exact host is decided by its owner alone, the wildcard FQDNs matching it are only looked up, the closest
+	// first, if it's not owned. Only the FQDNs of the ingress class of the HTTPProxy object are considered.
+	fqdns := []string{host}
+
+	for _, parent := range getParentDomains(host) {
+		fqdns = append(fqdns, "*."+parent)
+	}
+
+	for _, fqdn := range fqdns {
+		owner, err := getFqdnOwner(cr, cr.newIngressClass.name, fqdn)
+		if err != nil {
+			return false, err
+		}
+
+		if owner != nil {
+			return owner.Namespace == cr.newObj.Namespace, nil
+		}
+	}
+
+	return false, nil
+}
+
+// getFqdnOwner returns the owner of the FQDN, or nil if it's not owned.
+func getFqdnOwner(cr *checkRequest, ingressClassName, fqdn string) (*types.NamespacedName, error) {
+	entry, err := cr.cache.LookupEntry(utils.GenerateCacheKey(ingressClassName, fqdn))
+	if err != nil {
+		return nil, err
+	}
+
+	if entry != nil {
+		return &entry.Owner, nil
+	}
+
+	// In the stateless mode, the cache only holds the reservations and the persisted owners are indexed.
+	if statelessFqdnLookup {
+		return getIndexedFqdnOwner(cr, utils.GenerateFqdnIndexKey(ingressClassName, fqdn))
+	}
+
+	return nil, nil
+}
+
+// getHostWithoutPort returns the lowercased host of a Host header value, which may carry a port. IPv6 literals are
+// enclosed in brackets when followed by a port.
+func getHostWithoutPort(hostport string) string {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+	}
+
+	return strings.ToLower(host)
+}
+
+// getHostTargets returns the hosts targeted by the Host header rewrites and the redirects of the HTTPProxy object.
+func getHostTargets(httpproxy *contourv1.HTTPProxy) []hostTarget {
+	targets := make([]hostTarget, 0)
+
+	for i, route := range httpproxy.Spec.Routes {
+		path := fmt.Sprintf("spec.routes[%d]", i)
+
+		targets = append(targets, getHostRewriteTargets(path+".requestHeadersPolicy", route.RequestHeadersPolicy)...)
+
+		for j, service := range route.Services {
+			targets = append(targets,
+				getHostRewriteTargets(fmt.Sprintf("%s.services[%d].requestHeadersPolicy", path, j), service.RequestHeadersPolicy)...)
+		}
+
+		if route.RequestRedirectPolicy != nil && route.RequestRedirectPolicy.Hostname != nil {
+			targets = append(targets, hostTarget{
+				path: path + ".requestRedirectPolicy.hostname",
+				host: *route.RequestRedirectPolicy.Hostname,
+			})
+		}
+	}
+
+	return targets
+}
+
+func getHostRewriteTargets(path string, policy *contourv1.HeadersPolicy) []hostTarget {
+	targets := make([]hostTarget, 0)
+
+	if policy == nil {
+		return targets
+	}
+
+	for i, header := range policy.Set {
+		if strings.EqualFold(header.Name, "Host") {
+			targets = append(targets, hostTarget{
+				path: fmt.Sprintf("%s.set[%d]", path, i),
+				host: header.Value,
+			})
+		}
+	}
+
+	return targets
+}
+
+// matchHost matches the host against the pattern, a leading "*." in the pattern matches all the subdomains.
+func matchHost(pattern, host string) bool {
+	if suffix, found := strings.CutPrefix(pattern, "*"); found {
+		return strings.HasSuffix(host, suffix)
+	}
+
+	return pattern == host
+}
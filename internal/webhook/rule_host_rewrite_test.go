@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckHostRewrite(t *testing.T) {
+	if err := config.InitializeConfig("../../hack/config.yaml"); err != nil {
+		assert.FailNow(t, fmt.Sprintf("error reading the config file: %s", err.Error()))
+	}
+
+	testCache := cache.NewCache(time.Minute)
+	testCache.Set("private/owned.local", &types.NamespacedName{Namespace: "test", Name: "owned"}, 0)
+	testCache.Set("private/other.local", &types.NamespacedName{Namespace: "other", Name: "other"}, 0)
+	testCache.Set("private/*.wildcard.local", &types.NamespacedName{Namespace: "test", Name: "wildcard"}, 0)
+	testCache.Set("private/*.other-wildcard.local", &types.NamespacedName{Namespace: "other", Name: "wildcard"}, 0)
+	testCache.Set("private/api.wildcard.local", &types.NamespacedName{Namespace: "other", Name: "api"}, 0)
+	testCache.Set("public/public.local", &types.NamespacedName{Namespace: "test", Name: "public"}, 0)
+
+	checker := &checkHostRewrite{
+		allowedHosts:      []string{"*.example.com"},
+		domainDelegations: []config.DomainDelegation{{Domain: "delegated.local", Namespaces: []string{"test"}}},
+	}
+
+	newHttpproxy := func(route contourv1.Route) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "test"},
+			Spec: contourv1.HTTPProxySpec{
+				VirtualHost: &contourv1.VirtualHost{Fqdn: "test.local"},
+				Routes:      []contourv1.Route{route},
+			},
+		}
+	}
+
+	hostRewrite := func(host string) contourv1.Route {
+		return contourv1.Route{RequestHeadersPolicy: &contourv1.HeadersPolicy{Set: []contourv1.HeaderValue{{Name: "Host", Value: host}}}}
+	}
+
+	redirect := func(host string) contourv1.Route {
+		return contourv1.Route{RequestRedirectPolicy: &contourv1.HTTPRequestRedirectPolicy{Hostname: &host}}
+	}
+
+	tests := []struct {
+		name    string
+		route   contourv1.Route
+		allowed bool
+	}{
+		{name: "Should allow a rewrite into a host owned by the namespace", route: hostRewrite("owned.local"), allowed: true},
+		{name: "Should deny a rewrite into a host owned by another namespace", route: hostRewrite("other.local"), allowed: false},
+		{name: "Should deny a redirect into a host owned by another namespace", route: redirect("other.local"), allowed: false},
+		{name: "Should deny a redirect into a host without owner", route: redirect("unknown.local"), allowed: false},
+		{name: "Should allow a redirect into an allowed host", route: redirect("www.example.com"), allowed: true},
+		{name: "Should allow a rewrite into a delegated domain", route: hostRewrite("api.delegated.local:8080"), allowed: true},
+		{name: "Should allow a rewrite into a host owned by the namespace with a port", route: hostRewrite("Owned.local:8080"), allowed: true},
+		{name: "Should allow a rewrite into a host covered by a wildcard of the namespace", route: hostRewrite("api.v1.wildcard.local"), allowed: true},
+		{name: "Should deny a rewrite into a host covered by a wildcard of another namespace", route: hostRewrite("api.other-wildcard.local"), allowed: false},
+		{name: "Should deny a rewrite into an IPv6 literal", route: hostRewrite("[::1]:8080"), allowed: false},
+		{name: "Should deny a rewrite into a host owned by another namespace under a wildcard of the namespace", route: hostRewrite("api.wildcard.local"), allowed: false},
+		{name: "Should deny a redirect into a host owned by the namespace in another ingress class", route: redirect("public.local"), allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := checker.check(&checkRequest{
+				newObj:          newHttpproxy(tt.route),
+				cache:           testCache,
+				newIngressClass: &ingressClass{name: "private", valid: true},
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+		})
+	}
+
+	t.Run("Should allow an update not changing the spec of an object targeting a host without owner", func(t *testing.T) {
+		oldObj := newHttpproxy(redirect("unknown.local"))
+		newObj := oldObj.DeepCopy()
+		newObj.Finalizers = []string{"test"}
+
+		response, err := checker.check(&checkRequest{
+			newObj:          newObj,
+			oldObj:          oldObj,
+			cache:           testCache,
+			newIngressClass: &ingressClass{name: "private", valid: true},
+			oldIngressClass: &ingressClass{name: "private", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+
+	t.Run("Should allow an update of an object being deleted", func(t *testing.T) {
+		newObj := newHttpproxy(redirect("unknown.local"))
+		newObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		response, err := checker.check(&checkRequest{newObj: newObj, cache: testCache})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+}
+
+func TestCheckHostRewriteStateless(t *testing.T) {
+	if err := config.InitializeConfig("../../hack/config.yaml"); err != nil {
+		assert.FailNow(t, fmt.Sprintf("error reading the config file: %s", err.Error()))
+	}
+
+	statelessFqdnLookup = true
+	defer func() { statelessFqdnLookup = false }()
+
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(contourv1.AddToScheme(testScheme))
+
+	newHttpproxy := func(namespace, name, fqdn, redirectHost string) *contourv1.HTTPProxy {
+		httpproxy := &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: contourv1.HTTPProxySpec{
+				IngressClassName: "private",
+				VirtualHost:      &contourv1.VirtualHost{Fqdn: fqdn},
+			},
+		}
+
+		if redirectHost != "" {
+			httpproxy.Spec.Routes = []contourv1.Route{{RequestRedirectPolicy: &contourv1.HTTPRequestRedirectPolicy{Hostname: &redirectHost}}}
+		}
+
+		return httpproxy
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithObjects(newHttpproxy("test", "owned", "owned.local", ""), newHttpproxy("other", "other", "other.local", "")).
+		WithIndex(&contourv1.HTTPProxy{}, utils.FqdnIndexField, utils.IndexFqdn).
+		Build()
+
+	// The persisted owners are not cached in the stateless mode.
+	testCache := cache.NewCache(time.Minute)
+	defer func() { testCache.CleanUpStopChan <- true }()
+
+	checker := &checkHostRewrite{}
+
+	tests := []struct {
+		name    string
+		host    string
+		allowed bool
+	}{
+		{name: "Should allow a redirect into an indexed host owned by the namespace", host: "Owned.local", allowed: true},
+		{name: "Should deny a redirect into an indexed host owned by another namespace", host: "other.local", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := checker.check(&checkRequest{
+				newObj:          newHttpproxy("test", "test", "test.local", tt.host),
+				cache:           testCache,
+				client:          fakeClient,
+				newIngressClass: &ingressClass{name: "private", valid: true},
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+		})
+	}
+}
+
+func TestGetHostWithoutPort(t *testing.T) {
+	assert.Equal(t, "echo.example.com", getHostWithoutPort("Echo.Example.com:8080"))
+	assert.Equal(t, "echo.example.com", getHostWithoutPort("echo.example.com"))
+	assert.Equal(t, "::1", getHostWithoutPort("[::1]:8080"))
+	assert.Equal(t, "::1", getHostWithoutPort("[::1]"))
+	assert.Equal(t, "::1", getHostWithoutPort("::1"))
+}
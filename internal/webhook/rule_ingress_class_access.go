@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type checkIngressClassAccess struct {
+	next     checker
+	accesses map[string]config.IngressClassAccess // map[ingressClassName]config.IngressClassAccess
+}
+
+//nolint:varnamelen
+func (cica checkIngressClassAccess) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	// The newIngressClass object should be initialized and populated by previous rules.
+	if cr.newIngressClass == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "ingressClass struct is nil"}
+	}
+
+	access, found := cica.accesses[cr.newIngressClass.name]
+
+	// Updates not changing the spec, e.g. adding or removing finalizers by the controller, are not restricted.
+	if !found || isSpecUnchanged(cr) {
+		if cica.next != nil {
+			return cica.next.check(cr)
+		}
+
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+
+	allowed := slices.Contains(access.Users, cr.userInfo.Username) ||
+		slices.ContainsFunc(cr.userInfo.Groups, func(group string) bool {
+			return slices.Contains(access.Groups, group)
+		})
+
+	// Audit the decision along with the user identity.
+	logger.Info("ingress class access decision",
+		"allowed", allowed,
+		"ingressClassName", cr.newIngressClass.name,
+		"namespace", cr.newObj.Namespace,
+		"name", cr.newObj.Name,
+		"user", cr.userInfo.Username,
+		"groups", cr.userInfo.Groups,
+	)
+
+	if !allowed {
+		return &admissionv1.AdmissionResponse{Allowed: false,
+			Result: &metav1.Status{
+				// http code and message returned to the user
+				Code: http.StatusForbidden,
+				Message: fmt.Sprintf("user %s is not allowed to use ingressClassName %s",
+					cr.userInfo.Username, cr.newIngressClass.name),
+			}}, nil
+	}
+
+	if cica.next != nil {
+		return cica.next.check(cr)
+	}
+
+	return &admissionv1.AdmissionResponse{Allowed: true}, nil
+}
+
+func (cica *checkIngressClassAccess) setNext(c checker) {
+	cica.next = c
+}
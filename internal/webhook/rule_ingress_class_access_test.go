@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"testing"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+func TestCheckIngressClassAccess(t *testing.T) {
+	checker := &checkIngressClassAccess{
+		accesses: map[string]config.IngressClassAccess{
+			"inter-dc": {
+				IngressClassName: "inter-dc",
+				Users:            []string{"system:serviceaccount:platform:deployer"},
+				Groups:           []string{"platform"},
+			},
+		},
+	}
+
+	httpproxy := &contourv1.HTTPProxy{Spec: contourv1.HTTPProxySpec{IngressClassName: "inter-dc"}}
+
+	tests := []struct {
+		name            string
+		ingressClass    string
+		oldIngressClass *ingressClass
+		userInfo        authenticationv1.UserInfo
+		allowed         bool
+	}{
+		{
+			name:         "Should allow an unrestricted ingress class",
+			ingressClass: "private",
+			userInfo:     authenticationv1.UserInfo{Username: "developer"},
+			allowed:      true,
+		},
+		{
+			name:         "Should allow a listed user",
+			ingressClass: "inter-dc",
+			userInfo:     authenticationv1.UserInfo{Username: "system:serviceaccount:platform:deployer"},
+			allowed:      true,
+		},
+		{
+			name:         "Should allow a member of a listed group",
+			ingressClass: "inter-dc",
+			userInfo:     authenticationv1.UserInfo{Username: "admin", Groups: []string{"platform"}},
+			allowed:      true,
+		},
+		{
+			name:         "Should deny any other user",
+			ingressClass: "inter-dc",
+			userInfo:     authenticationv1.UserInfo{Username: "developer", Groups: []string{"developers"}},
+			allowed:      false,
+		},
+		{
+			name:            "Should allow updates not changing the spec",
+			ingressClass:    "inter-dc",
+			oldIngressClass: &ingressClass{name: "inter-dc", valid: true},
+			userInfo:        authenticationv1.UserInfo{Username: "system:serviceaccount:webhook:webhook"},
+			allowed:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := checker.check(&checkRequest{
+				newObj:          httpproxy,
+				oldObj:          httpproxy,
+				userInfo:        tt.userInfo,
+				newIngressClass: &ingressClass{name: tt.ingressClass, valid: true},
+				oldIngressClass: tt.oldIngressClass,
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+		})
+	}
+}
@@ -1,13 +1,28 @@
 package webhook
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"slices"
 
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
 	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 )
 
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// namespaceIngressClassPolicy is the parsed form of config.NamespaceIngressClassPolicy.
+type namespaceIngressClassPolicy struct {
+	selector       labels.Selector
+	ingressClasses []string
+}
+
 type checkIngressClassNameOnCreate struct {
 	next checker
 }
@@ -44,16 +59,21 @@ func (cicnoc checkIngressClassNameOnCreate) check(cr *checkRequest) (*admissionv
 			}}, nil
 	}
 
+	response, err := checkNamespaceIngressClass(cr, newIngressClassName)
+	if response != nil || err != nil {
+		return response, err
+	}
+
+	if response := checkIngressClassApprovalAnnotation(cr); response != nil {
+		return response, nil
+	}
+
 	cr.newIngressClass = &ingressClass{
 		name:  newIngressClassName,
 		valid: true,
 	}
 
-	if cicnoc.next != nil {
-		return cicnoc.next.check(cr)
-	}
-
-	return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	return checkNextWithWarnings(cicnoc.next, cr, getIngressClassNameWarnings(cr))
 }
 
 func (cicnoc *checkIngressClassNameOnCreate) setNext(c checker) {
@@ -87,6 +107,26 @@ func (cicnou checkIngressClassNameOnUpdate) check(cr *checkRequest) (*admissionv
 	oldIngressClassName := utils.GetIngressClassName(cr.oldObj)
 	isOldIngressClassNameValid := utils.ValidateIngressClassName(oldIngressClassName)
 
+	// The namespace entitlement is only checked when the ingressClassName is changed, so the existing objects
+	// can still be updated, e.g. to remove finalizers, after the namespace loses the entitlement.
+	if newIngressClassName != oldIngressClassName {
+		response, err := checkNamespaceIngressClass(cr, newIngressClassName)
+		if response != nil || err != nil {
+			return response, err
+		}
+	}
+
+	if response := checkIngressClassApprovalAnnotation(cr); response != nil {
+		return response, nil
+	}
+
+	// Objects with an invalid ingressClassName can move to any valid one.
+	if isOldIngressClassNameValid && newIngressClassName != oldIngressClassName {
+		if response := checkIngressClassTransition(cr, oldIngressClassName, newIngressClassName); response != nil {
+			return response, nil
+		}
+	}
+
 	cr.newIngressClass = &ingressClass{
 		name:  newIngressClassName,
 		valid: true,
@@ -96,11 +136,7 @@ func (cicnou checkIngressClassNameOnUpdate) check(cr *checkRequest) (*admissionv
 		valid: isOldIngressClassNameValid,
 	}
 
-	if cicnou.next != nil {
-		return cicnou.next.check(cr)
-	}
-
-	return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	return checkNextWithWarnings(cicnou.next, cr, getIngressClassNameWarnings(cr))
 }
 
 func (cicnou *checkIngressClassNameOnUpdate) setNext(c checker) {
@@ -128,3 +164,91 @@ func (cicnod checkIngressClassNameOnDelete) check(cr *checkRequest) (*admissionv
 func (cicnod *checkIngressClassNameOnDelete) setNext(c checker) {
 	cicnod.next = c
 }
+
+// getIngressClassNameWarnings returns a warning if the ingress class annotation and spec.ingressClassName disagree.
+func getIngressClassNameWarnings(cr *checkRequest) []string {
+	if mismatch := utils.GetIngressClassNameMismatch(cr.newObj); mismatch != "" {
+		return []string{mismatch}
+	}
+
+	return nil
+}
+
+// newNamespaceIngressClassPolicies parses the label selectors of the namespace ingress class policies.
+func newNamespaceIngressClassPolicies(cfg config.NamespaceIngressClasses) ([]namespaceIngressClassPolicy, error) {
+	policies := make([]namespaceIngressClassPolicy, 0, len(cfg.Policies))
+
+	for _, policy := range cfg.Policies {
+		selector, err := metav1.LabelSelectorAsSelector(&policy.NamespaceSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		policies = append(policies, namespaceIngressClassPolicy{
+			selector:       selector,
+			ingressClasses: policy.IngressClasses,
+		})
+	}
+
+	return policies, nil
+}
+
+// checkNamespaceIngressClass returns a denying response if the namespace of the HTTPProxy object is not permitted
+// to use the ingressClassName, otherwise it returns nil.
+func checkNamespaceIngressClass(cr *checkRequest, ingressClassName string) (*admissionv1.AdmissionResponse, *httpErr) {
+	if len(namespaceIngressClassPolicies) == 0 {
+		return nil, nil
+	}
+
+	if cr.client == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "kubernetes client is nil"}
+	}
+
+	namespace := &corev1.Namespace{}
+
+	if err := cr.client.Get(context.Background(), types.NamespacedName{Name: cr.newObj.Namespace}, namespace); err != nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: fmt.Sprintf("failed to get the namespace: %s", err.Error())}
+	}
+
+	permittedIngressClasses := getPermittedIngressClasses(labels.Set(namespace.Labels))
+
+	if slices.Contains(permittedIngressClasses, ingressClassName) {
+		return nil, nil
+	}
+
+	return &admissionv1.AdmissionResponse{Allowed: false,
+		Result: &metav1.Status{
+			// http code and message returned to the user
+			Code: http.StatusForbidden,
+			Message: fmt.Sprintf("ingressClassName %s is not permitted in namespace %s, permitted ingressClassNames: %v",
+				ingressClassName, cr.newObj.Namespace, permittedIngressClasses),
+		}}, nil
+}
+
+// getPermittedIngressClasses returns the ingressClassNames permitted by all the policies selecting the namespace labels.
+func getPermittedIngressClasses(namespaceLabels labels.Set) []string {
+	permittedIngressClasses := make([]string, 0)
+	selected := false
+
+	for _, policy := range namespaceIngressClassPolicies {
+		if !policy.selector.Matches(namespaceLabels) {
+			continue
+		}
+
+		selected = true
+
+		for _, ingressClassName := range policy.ingressClasses {
+			if !slices.Contains(permittedIngressClasses, ingressClassName) {
+				permittedIngressClasses = append(permittedIngressClasses, ingressClassName)
+			}
+		}
+	}
+
+	if !selected {
+		return rulesConfig.NamespaceIngressClasses.DefaultIngressClasses
+	}
+
+	return permittedIngressClasses
+}
@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"fmt"
+	"testing"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckIngressClassNameNamespacePolicy(t *testing.T) {
+	if err := config.InitializeConfig("../../hack/config.yaml"); err != nil {
+		assert.FailNow(t, fmt.Sprintf("error reading the config file: %s", err.Error()))
+	}
+
+	rulesConfig.NamespaceIngressClasses = config.NamespaceIngressClasses{
+		Policies: []config.NamespaceIngressClassPolicy{
+			{
+				NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"exposure": "public"}},
+				IngressClasses:    []string{"public", "private"},
+			},
+		},
+		DefaultIngressClasses: []string{"private"},
+	}
+
+	policies, err := newNamespaceIngressClassPolicies(rulesConfig.NamespaceIngressClasses)
+	assert.Nil(t, err)
+
+	namespaceIngressClassPolicies = policies
+
+	defer func() {
+		rulesConfig = config.Rules{}
+		namespaceIngressClassPolicies = nil
+	}()
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(clientgoscheme.Scheme).
+		WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "exposed", Labels: map[string]string{"exposure": "public"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "internal"}},
+		).
+		Build()
+
+	tests := []struct {
+		name             string
+		namespace        string
+		ingressClassName string
+		allowed          bool
+	}{
+		{name: "Should allow an ingress class permitted by a policy", namespace: "exposed", ingressClassName: "public", allowed: true},
+		{name: "Should allow a default ingress class", namespace: "internal", ingressClassName: "private", allowed: true},
+		{name: "Should deny an ingress class not permitted in the namespace", namespace: "internal", ingressClassName: "public", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &checkIngressClassNameOnCreate{}
+
+			response, err := checker.check(&checkRequest{
+				newObj: &contourv1.HTTPProxy{
+					ObjectMeta: metav1.ObjectMeta{Namespace: tt.namespace, Name: "test"},
+					Spec:       contourv1.HTTPProxySpec{IngressClassName: tt.ingressClassName},
+				},
+				client: fakeClient,
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+		})
+	}
+}
+
+func TestCheckIngressClassNameTransitionPolicy(t *testing.T) {
+	if err := config.InitializeConfig("../../hack/config.yaml"); err != nil {
+		assert.FailNow(t, fmt.Sprintf("error reading the config file: %s", err.Error()))
+	}
+
+	approvalAnnotation := "snappcloud.io/approved-ingress-class"
+
+	rulesConfig.IngressClassTransitions = config.IngressClassTransitions{
+		Enabled:            true,
+		ApprovalAnnotation: approvalAnnotation,
+		ApproverGroups:     []string{"platform"},
+		Transitions: []config.IngressClassTransition{
+			{From: "test", To: "private"},
+			{From: "private", To: "public", RequireApproval: true},
+		},
+	}
+
+	defer func() {
+		rulesConfig = config.Rules{}
+	}()
+
+	newHttpproxy := func(ingressClassName string, annotations map[string]string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "test", Annotations: annotations},
+			Spec:       contourv1.HTTPProxySpec{IngressClassName: ingressClassName},
+		}
+	}
+
+	approved := map[string]string{approvalAnnotation: "public"}
+
+	tests := []struct {
+		name    string
+		oldObj  *contourv1.HTTPProxy
+		newObj  *contourv1.HTTPProxy
+		groups  []string
+		allowed bool
+	}{
+		{
+			name:    "Should allow a transition without approval",
+			oldObj:  newHttpproxy("test", nil),
+			newObj:  newHttpproxy("private", nil),
+			allowed: true,
+		},
+		{
+			name:    "Should deny a transition which is not listed",
+			oldObj:  newHttpproxy("test", nil),
+			newObj:  newHttpproxy("public", nil),
+			allowed: false,
+		},
+		{
+			name:    "Should deny a transition requiring approval without the annotation",
+			oldObj:  newHttpproxy("private", nil),
+			newObj:  newHttpproxy("public", nil),
+			allowed: false,
+		},
+		{
+			name:    "Should deny setting the approval annotation by a user who is not an approver",
+			oldObj:  newHttpproxy("private", nil),
+			newObj:  newHttpproxy("public", approved),
+			groups:  []string{"developers"},
+			allowed: false,
+		},
+		{
+			name:    "Should allow a transition approved in the same request by an approver",
+			oldObj:  newHttpproxy("private", nil),
+			newObj:  newHttpproxy("public", approved),
+			groups:  []string{"platform"},
+			allowed: true,
+		},
+		{
+			name:    "Should deny a transition relying on an approval of a previous request",
+			oldObj:  newHttpproxy("private", approved),
+			newObj:  newHttpproxy("public", approved),
+			groups:  []string{"developers"},
+			allowed: false,
+		},
+		{
+			name:    "Should deny an approver relying on an approval annotation left from a previous request",
+			oldObj:  newHttpproxy("private", approved),
+			newObj:  newHttpproxy("public", approved),
+			groups:  []string{"platform"},
+			allowed: false,
+		},
+		{
+			name:    "Should allow a transition approved by an approver changing a previous approval annotation",
+			oldObj:  newHttpproxy("private", map[string]string{approvalAnnotation: "private"}),
+			newObj:  newHttpproxy("public", approved),
+			groups:  []string{"platform"},
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &checkIngressClassNameOnUpdate{}
+
+			response, err := checker.check(&checkRequest{
+				newObj:   tt.newObj,
+				oldObj:   tt.oldObj,
+				userInfo: authenticationv1.UserInfo{Username: "test", Groups: tt.groups},
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkIngressClassApprovalAnnotation returns a denying response if the approval annotation is added or changed by a
+// user who is not a member of the approver groups, otherwise it returns nil.
+func checkIngressClassApprovalAnnotation(cr *checkRequest) *admissionv1.AdmissionResponse {
+	transitions := rulesConfig.IngressClassTransitions
+
+	if !transitions.Enabled || transitions.ApprovalAnnotation == "" {
+		return nil
+	}
+
+	newApproval, found := cr.newObj.Annotations[transitions.ApprovalAnnotation]
+	if !found {
+		return nil
+	}
+
+	if cr.oldObj != nil {
+		if oldApproval, found := cr.oldObj.Annotations[transitions.ApprovalAnnotation]; found && oldApproval == newApproval {
+			return nil
+		}
+	}
+
+	if isApprover(cr, transitions) {
+		logger.Info("ingress class approval annotation is set",
+			"namespace", cr.newObj.Namespace, "name", cr.newObj.Name, "approval", newApproval, "user", cr.userInfo.Username)
+
+		return nil
+	}
+
+	return &admissionv1.AdmissionResponse{Allowed: false,
+		Result: &metav1.Status{
+			// http code and message returned to the user
+			Code: http.StatusForbidden,
+			Message: fmt.Sprintf("the %s annotation can only be set by members of groups %v",
+				transitions.ApprovalAnnotation, transitions.ApproverGroups),
+		}}
+}
+
+// checkIngressClassTransition returns a denying response if the ingressClassName change is not allowed or requires
+// an approval which is not given, otherwise it returns nil.
+func checkIngressClassTransition(cr *checkRequest, oldIngressClassName, newIngressClassName string) *admissionv1.AdmissionResponse {
+	transitions := rulesConfig.IngressClassTransitions
+
+	if !transitions.Enabled {
+		return nil
+	}
+
+	index := slices.IndexFunc(transitions.Transitions, func(transition config.IngressClassTransition) bool {
+		return transition.From == oldIngressClassName && transition.To == newIngressClassName
+	})
+
+	if index == -1 {
+		return &admissionv1.AdmissionResponse{Allowed: false,
+			Result: &metav1.Status{
+				// http code and message returned to the user
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("changing ingressClassName from %s to %s is not allowed", oldIngressClassName, newIngressClassName),
+			}}
+	}
+
+	if !transitions.Transitions[index].RequireApproval {
+		return nil
+	}
+
+	// The approval only applies to the request setting it, so an annotation left from a previous transition can not
+	// approve a later one, e.g. moving back to the same ingressClassName.
+	approval := cr.newObj.Annotations[transitions.ApprovalAnnotation]
+
+	if cr.oldObj != nil && cr.oldObj.Annotations[transitions.ApprovalAnnotation] == approval {
+		approval = ""
+	}
+
+	if isApprover(cr, transitions) && approval == newIngressClassName {
+		return nil
+	}
+
+	return &admissionv1.AdmissionResponse{Allowed: false,
+		Result: &metav1.Status{
+			// http code and message returned to the user
+			Code: http.StatusForbidden,
+			Message: fmt.Sprintf("changing ingressClassName from %s to %s requires the %s annotation set to %s in the same request by members of groups %v",
+				oldIngressClassName, newIngressClassName, transitions.ApprovalAnnotation, newIngressClassName, transitions.ApproverGroups),
+		}}
+}
+
+func isApprover(cr *checkRequest, transitions config.IngressClassTransitions) bool {
+	for _, group := range cr.userInfo.Groups {
+		if slices.Contains(transitions.ApproverGroups, group) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// defaultLocalRateLimitMaxBurstRatio is the default ratio of the burst to the requests per unit.
+const defaultLocalRateLimitMaxBurstRatio = 10
+
+// localRateLimitUnitSeconds maps the units of the local rate limit policies to their length in seconds.
+var localRateLimitUnitSeconds = map[string]int64{"second": 1, "minute": 60, "hour": 3600}
+
+type checkLocalRateLimit struct {
+	next     checker
+	mode     string
+	policies map[string]config.LocalRateLimitPolicy // map[ingressClassName]config.LocalRateLimitPolicy
+	// maxBurstRatio bounds the burst relative to the requests per unit.
+	maxBurstRatio int64
+}
+
+//nolint:varnamelen
+func (clrl checkLocalRateLimit) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	if isPolicyExempt(cr) {
+		return checkNextWithWarnings(clrl.next, cr, nil)
+	}
+
+	// The newIngressClass object should be initialized and populated by previous rules.
+	if cr.newIngressClass == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "ingressClass struct is nil"}
+	}
+
+	policy := clrl.policies[cr.newIngressClass.name]
+	violations := make([]string, 0)
+	warnings := make([]string, 0)
+
+	var virtualHostPolicy *contourv1.RateLimitPolicy
+
+	if cr.newObj.Spec.VirtualHost != nil {
+		virtualHostPolicy = cr.newObj.Spec.VirtualHost.RateLimitPolicy
+
+		if virtualHostPolicy != nil && virtualHostPolicy.Local != nil {
+			violations = clrl.appendLocalRateLimitViolations(violations, "spec.virtualhost.rateLimitPolicy.local", policy, virtualHostPolicy.Local)
+		}
+	}
+
+	for i, route := range cr.newObj.Spec.Routes {
+		path := fmt.Sprintf("spec.routes[%d].rateLimitPolicy", i)
+
+		if route.RateLimitPolicy != nil && route.RateLimitPolicy.Local != nil {
+			violations = clrl.appendLocalRateLimitViolations(violations, path+".local", policy, route.RateLimitPolicy.Local)
+		}
+
+		// Both the virtualhost and the route rate limit policies apply to the route.
+		globalRequestsPerDay := minGlobalRequestsPerDay(virtualHostPolicy, route.RateLimitPolicy)
+		if globalRequestsPerDay == 0 {
+			continue
+		}
+
+		if virtualHostPolicy != nil && virtualHostPolicy.Local != nil &&
+			localRequestsPerDay(virtualHostPolicy.Local) >= globalRequestsPerDay {
+			warnings = append(warnings, fmt.Sprintf("spec.virtualhost.rateLimitPolicy.local: never applies to spec.routes[%d], as the global rate limit is lower", i))
+		}
+
+		if route.RateLimitPolicy != nil && route.RateLimitPolicy.Local != nil &&
+			localRequestsPerDay(route.RateLimitPolicy.Local) >= globalRequestsPerDay {
+			warnings = append(warnings, fmt.Sprintf("%s.local: never applies, as the global rate limit is lower", path))
+		}
+	}
+
+	return reportViolations(clrl.mode, clrl.next, cr, violations, warnings)
+}
+
+func (clrl *checkLocalRateLimit) setNext(c checker) {
+	clrl.next = c
+}
+
+// appendLocalRateLimitViolations appends the violations of the requests, unit and burst combination and of the
+// ingress class ceilings.
+func (clrl checkLocalRateLimit) appendLocalRateLimitViolations(violations []string, path string, policy config.LocalRateLimitPolicy,
+	local *contourv1.LocalRateLimitPolicy) []string {
+	unitSeconds, found := localRateLimitUnitSeconds[local.Unit]
+	if !found {
+		return append(violations, fmt.Sprintf("%s.unit: must be one of second, minute or hour", path))
+	}
+
+	if local.Requests == 0 {
+		return append(violations, fmt.Sprintf("%s.requests: must allow at least 1 request per unit", path))
+	}
+
+	// The token bucket holds requests+burst tokens, so a burst far beyond the requests defeats the rate within a unit.
+	if int64(local.Burst) > clrl.maxBurstRatio*int64(local.Requests) {
+		violations = append(violations, fmt.Sprintf("%s.burst: must not exceed %d times the requests per unit (%d)",
+			path, clrl.maxBurstRatio, local.Requests))
+	}
+
+	if policy.MaxRequestsPerSecond > 0 && int64(local.Requests) > policy.MaxRequestsPerSecond*unitSeconds {
+		violations = append(violations, fmt.Sprintf("%s: %d/%s exceeds %d requests per second",
+			path, local.Requests, local.Unit, policy.MaxRequestsPerSecond))
+	}
+
+	if policy.MaxBurst > 0 && int64(local.Burst) > policy.MaxBurst {
+		violations = append(violations, fmt.Sprintf("%s.burst: must not exceed %d", path, policy.MaxBurst))
+	}
+
+	if local.ResponseStatusCode != 0 && (local.ResponseStatusCode < 400 || local.ResponseStatusCode > 599) {
+		violations = append(violations, fmt.Sprintf("%s.responseStatusCode: must be in the 400-599 range", path))
+	}
+
+	return violations
+}
+
+// localRateLimitMaxBurstRatio returns the configured ratio of the burst to the requests per unit or the default one
+// if not set.
+func localRateLimitMaxBurstRatio(ratio int64) int64 {
+	if ratio <= 0 {
+		return defaultLocalRateLimitMaxBurstRatio
+	}
+
+	return ratio
+}
+
+// localRequestsPerDay normalizes the rate of a local rate limit policy to requests per day.
+func localRequestsPerDay(local *contourv1.LocalRateLimitPolicy) int64 {
+	unitSeconds, found := localRateLimitUnitSeconds[local.Unit]
+	if !found {
+		return 0
+	}
+
+	return int64(local.Requests) * (secondsPerDay / unitSeconds)
+}
+
+// minGlobalRequestsPerDay returns the lowest rate of the unconditional global rate limit descriptors, i.e. made of a
+// single genericKey entry, normalized to requests per day. It returns zero if there is no such descriptor.
+func minGlobalRequestsPerDay(policies ...*contourv1.RateLimitPolicy) int64 {
+	var minRequestsPerDay int64
+
+	for _, policy := range policies {
+		if policy == nil || policy.Global == nil {
+			continue
+		}
+
+		for _, descriptor := range policy.Global.Descriptors {
+			if len(descriptor.Entries) != 1 || descriptor.Entries[0].GenericKey == nil {
+				continue
+			}
+
+			requestsPerDay := toRequestsPerDay(parseGlobalRateLimitValue(descriptor.Entries[0].GenericKey.Value))
+			if requestsPerDay > 0 && (minRequestsPerDay == 0 || requestsPerDay < minRequestsPerDay) {
+				minRequestsPerDay = requestsPerDay
+			}
+		}
+	}
+
+	return minRequestsPerDay
+}
+
+// parseGlobalRateLimitValue splits a genericKey value in the form of "requests/unit" as expected by the rlsparser.
+// The rlsparser is not used since it ignores the virtualhost rate limit policy.
+func parseGlobalRateLimitValue(value string) (string, string) {
+	requestsPerUnit, unit, _ := strings.Cut(value, "/")
+
+	return requestsPerUnit, unit
+}
@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckLocalRateLimit(t *testing.T) {
+	checker := &checkLocalRateLimit{
+		mode:          config.RuleModeEnforce,
+		maxBurstRatio: defaultLocalRateLimitMaxBurstRatio,
+		policies: map[string]config.LocalRateLimitPolicy{
+			"public": {IngressClassName: "public", MaxRequestsPerSecond: 100, MaxBurst: 10},
+		},
+	}
+
+	newHttpproxy := func(local *contourv1.LocalRateLimitPolicy, globalValue string) *contourv1.HTTPProxy {
+		rateLimitPolicy := &contourv1.RateLimitPolicy{Local: local}
+
+		if globalValue != "" {
+			rateLimitPolicy.Global = &contourv1.GlobalRateLimitPolicy{
+				Descriptors: []contourv1.RateLimitDescriptor{{Entries: []contourv1.RateLimitDescriptorEntry{
+					{GenericKey: &contourv1.GenericKeyDescriptor{Key: "test.echo.limit", Value: globalValue}},
+				}}},
+			}
+		}
+
+		return &contourv1.HTTPProxy{Spec: contourv1.HTTPProxySpec{Routes: []contourv1.Route{{RateLimitPolicy: rateLimitPolicy}}}}
+	}
+
+	tests := []struct {
+		name             string
+		ingressClassName string
+		httpproxy        *contourv1.HTTPProxy
+		allowed          bool
+		warnings         int
+	}{
+		{
+			name:             "Should allow a local rate limit within the ceilings",
+			ingressClassName: "public",
+			httpproxy:        newHttpproxy(&contourv1.LocalRateLimitPolicy{Requests: 6000, Unit: "minute", Burst: 10}, ""),
+			allowed:          true,
+		},
+		{
+			name:             "Should deny requests exceeding the ceiling",
+			ingressClassName: "public",
+			httpproxy:        newHttpproxy(&contourv1.LocalRateLimitPolicy{Requests: 6001, Unit: "minute"}, ""),
+			allowed:          false,
+		},
+		{
+			name:             "Should deny a burst exceeding the ceiling",
+			ingressClassName: "public",
+			httpproxy:        newHttpproxy(&contourv1.LocalRateLimitPolicy{Requests: 100, Unit: "second", Burst: 11}, ""),
+			allowed:          false,
+		},
+		{
+			name:             "Should allow a burst exceeding the requests without a ceiling",
+			ingressClassName: "private",
+			httpproxy:        newHttpproxy(&contourv1.LocalRateLimitPolicy{Requests: 5, Unit: "second", Burst: 6}, ""),
+			allowed:          true,
+		},
+		{
+			name:             "Should deny a burst exceeding the ratio of the requests",
+			ingressClassName: "private",
+			httpproxy:        newHttpproxy(&contourv1.LocalRateLimitPolicy{Requests: 1, Unit: "second", Burst: 11}, ""),
+			allowed:          false,
+		},
+		{
+			name:             "Should deny an invalid unit",
+			ingressClassName: "private",
+			httpproxy:        newHttpproxy(&contourv1.LocalRateLimitPolicy{Requests: 5, Unit: "day"}, ""),
+			allowed:          false,
+		},
+		{
+			name:             "Should warn on a local rate limit never applied due to a lower global one",
+			ingressClassName: "private",
+			httpproxy:        newHttpproxy(&contourv1.LocalRateLimitPolicy{Requests: 60, Unit: "minute"}, "1/s"),
+			allowed:          true,
+			warnings:         1,
+		},
+		{
+			name:             "Should not warn on a local rate limit lower than the global one",
+			ingressClassName: "private",
+			httpproxy:        newHttpproxy(&contourv1.LocalRateLimitPolicy{Requests: 1, Unit: "second"}, "2/s"),
+			allowed:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := checker.check(&checkRequest{
+				newObj:          tt.httpproxy,
+				newIngressClass: &ingressClass{name: tt.ingressClassName, valid: true},
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+			assert.Len(t, response.Warnings, tt.warnings)
+		})
+	}
+
+	nonCompliant := newHttpproxy(&contourv1.LocalRateLimitPolicy{Requests: 6001, Unit: "minute"}, "")
+
+	t.Run("Should allow an update not changing the spec of a non-compliant object", func(t *testing.T) {
+		newObj := nonCompliant.DeepCopy()
+		newObj.Finalizers = []string{"test"}
+
+		response, err := checker.check(&checkRequest{
+			newObj:          newObj,
+			oldObj:          nonCompliant,
+			newIngressClass: &ingressClass{name: "public", valid: true},
+			oldIngressClass: &ingressClass{name: "public", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+
+	t.Run("Should allow an update of an object being deleted", func(t *testing.T) {
+		newObj := nonCompliant.DeepCopy()
+		newObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		response, err := checker.check(&checkRequest{
+			newObj:          newObj,
+			newIngressClass: &ingressClass{name: "public", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/snapp-incubator/contour-global-ratelimit-operator/pkg/rlsparser"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// secondsPerDay is the common unit the rates are normalized to, as every rate limit unit divides it.
+const secondsPerDay = 86400
+
+// rateLimitBudgetPolicy is the parsed form of config.RateLimitBudgetPolicy.
+type rateLimitBudgetPolicy struct {
+	selector             labels.Selector
+	maxRequestsPerSecond int64
+}
+
+type checkRateLimitBudget struct {
+	next                        checker
+	mode                        string
+	policies                    []rateLimitBudgetPolicy
+	defaultMaxRequestsPerSecond int64
+}
+
+//nolint:varnamelen
+func (crlb checkRateLimitBudget) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	if isPolicyExempt(cr) {
+		return checkNextWithWarnings(crlb.next, cr, nil)
+	}
+
+	newRequestsPerDay, parsed := getRateLimitRequestsPerDay(cr.newObj)
+
+	// Objects without global rate limits do not consume the budget and the descriptors which can not be parsed
+	// are reported by the rlsValidator.
+	if !parsed || newRequestsPerDay == 0 {
+		return checkNextWithWarnings(crlb.next, cr, nil)
+	}
+
+	if cr.client == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "kubernetes client is nil"}
+	}
+
+	namespace := &corev1.Namespace{}
+
+	if err := cr.client.Get(context.Background(), types.NamespacedName{Name: cr.newObj.Namespace}, namespace); err != nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: fmt.Sprintf("failed to get the namespace: %s", err.Error())}
+	}
+
+	maxRequestsPerSecond := crlb.getMaxRequestsPerSecond(labels.Set(namespace.Labels))
+	if maxRequestsPerSecond == 0 {
+		return checkNextWithWarnings(crlb.next, cr, nil)
+	}
+
+	// Updates not increasing the rates are allowed, so objects are not stuck if the budget is lowered.
+	if cr.oldObj != nil {
+		if oldRequestsPerDay, _ := getRateLimitRequestsPerDay(cr.oldObj); newRequestsPerDay <= oldRequestsPerDay {
+			return checkNextWithWarnings(crlb.next, cr, nil)
+		}
+	}
+
+	httpproxies := &contourv1.HTTPProxyList{}
+
+	if err := cr.client.List(context.Background(), httpproxies, client.InNamespace(cr.newObj.Namespace)); err != nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: fmt.Sprintf("failed to list the httpproxy objects: %s", err.Error())}
+	}
+
+	totalRequestsPerDay := newRequestsPerDay
+
+	for i := range httpproxies.Items {
+		if httpproxies.Items[i].Name == cr.newObj.Name {
+			continue
+		}
+
+		requestsPerDay, _ := getRateLimitRequestsPerDay(&httpproxies.Items[i])
+		totalRequestsPerDay += requestsPerDay
+	}
+
+	if totalRequestsPerDay <= maxRequestsPerSecond*secondsPerDay {
+		return checkNextWithWarnings(crlb.next, cr, nil)
+	}
+
+	violation := fmt.Sprintf("global rate limits of namespace %s would sum up to %.2f requests per second, exceeding the budget of %d requests per second",
+		cr.newObj.Namespace, float64(totalRequestsPerDay)/secondsPerDay, maxRequestsPerSecond)
+
+	return reportViolations(crlb.mode, crlb.next, cr, []string{violation}, nil)
+}
+
+func (crlb *checkRateLimitBudget) setNext(c checker) {
+	crlb.next = c
+}
+
+// getMaxRequestsPerSecond returns the budget of the first policy selecting the namespace or the default one.
+func (crlb checkRateLimitBudget) getMaxRequestsPerSecond(namespaceLabels labels.Set) int64 {
+	for _, policy := range crlb.policies {
+		if policy.selector.Matches(namespaceLabels) {
+			return policy.maxRequestsPerSecond
+		}
+	}
+
+	return crlb.defaultMaxRequestsPerSecond
+}
+
+func newRateLimitBudgetPolicies(cfg config.RateLimitBudgets) ([]rateLimitBudgetPolicy, error) {
+	policies := make([]rateLimitBudgetPolicy, 0, len(cfg.Policies))
+
+	for _, policy := range cfg.Policies {
+		selector, err := metav1.LabelSelectorAsSelector(&policy.NamespaceSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		policies = append(policies, rateLimitBudgetPolicy{
+			selector:             selector,
+			maxRequestsPerSecond: policy.MaxRequestsPerSecond,
+		})
+	}
+
+	return policies, nil
+}
+
+// getRateLimitRequestsPerDay returns the sum of the global rates applied by the rate limit service normalized to
+// requests per day. It returns false if the descriptors can not be parsed.
+func getRateLimitRequestsPerDay(httpproxy *contourv1.HTTPProxy) (int64, bool) {
+	_, policy, err := rlsparser.ParseGlobalRateLimit(httpproxy)
+	if err != nil {
+		return 0, false
+	}
+
+	var requestsPerDay int64
+
+	for _, descriptor := range policy.RateLimitsDescriptors {
+		requestsPerDay += toRequestsPerDay(descriptor.RateLimit.RequestsPerUnit, descriptor.RateLimit.Unit)
+
+		for _, nested := range descriptor.Descriptors {
+			requestsPerDay += toRequestsPerDay(nested.RateLimit.RequestsPerUnit, nested.RateLimit.Unit)
+		}
+	}
+
+	return requestsPerDay, true
+}
+
+// toRequestsPerDay normalizes a rate parsed by the rlsparser to requests per day.
+func toRequestsPerDay(requestsPerUnit, unit string) int64 {
+	requests, err := strconv.ParseInt(requestsPerUnit, 10, 64)
+	if err != nil || rateLimitUnitSeconds[unit] == 0 {
+		return 0
+	}
+
+	return requests * (secondsPerDay / rateLimitUnitSeconds[unit])
+}
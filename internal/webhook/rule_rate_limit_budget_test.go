@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckRateLimitBudget(t *testing.T) {
+	policies, err := newRateLimitBudgetPolicies(config.RateLimitBudgets{
+		Policies: []config.RateLimitBudgetPolicy{
+			{
+				NamespaceSelector:    metav1.LabelSelector{MatchLabels: map[string]string{"tier": "critical"}},
+				MaxRequestsPerSecond: 100,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	checker := &checkRateLimitBudget{mode: config.RuleModeEnforce, policies: policies, defaultMaxRequestsPerSecond: 10}
+
+	newHttpproxy := func(namespace, name, value string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: contourv1.HTTPProxySpec{Routes: []contourv1.Route{{
+				RateLimitPolicy: &contourv1.RateLimitPolicy{Global: &contourv1.GlobalRateLimitPolicy{
+					Descriptors: []contourv1.RateLimitDescriptor{{Entries: []contourv1.RateLimitDescriptorEntry{
+						{GenericKey: &contourv1.GenericKeyDescriptor{Key: namespace + "." + name + ".limit", Value: value}},
+					}}},
+				}},
+			}}},
+		}
+	}
+
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(testScheme))
+	utilruntime.Must(contourv1.AddToScheme(testScheme))
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "critical", Labels: map[string]string{"tier": "critical"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			newHttpproxy("critical", "existing", "60/s"),
+			newHttpproxy("test", "existing", "300/m"),
+		).
+		Build()
+
+	tests := []struct {
+		name    string
+		newObj  *contourv1.HTTPProxy
+		oldObj  *contourv1.HTTPProxy
+		allowed bool
+	}{
+		{name: "Should allow rates within the budget of a policy", newObj: newHttpproxy("critical", "echo", "40/s"), allowed: true},
+		{name: "Should deny rates exceeding the budget of a policy", newObj: newHttpproxy("critical", "echo", "41/s"), allowed: false},
+		{name: "Should normalize rates of different units", newObj: newHttpproxy("test", "echo", "18000/h"), allowed: true},
+		{name: "Should deny rates exceeding the default budget", newObj: newHttpproxy("test", "echo", "18001/h"), allowed: false},
+		{name: "Should replace the rates of the updated object", newObj: newHttpproxy("test", "existing", "10/s"), oldObj: newHttpproxy("test", "existing", "300/m"), allowed: true},
+		{name: "Should allow updates not increasing the rates", newObj: newHttpproxy("test", "existing", "300/m"), oldObj: newHttpproxy("test", "existing", "300/m"), allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := checker.check(&checkRequest{newObj: tt.newObj, oldObj: tt.oldObj, client: fakeClient})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+		})
+	}
+
+	overBudget := newHttpproxy("test", "over-budget", "1000/s")
+
+	t.Run("Should allow an update not changing the spec of an object exceeding the budget", func(t *testing.T) {
+		newObj := overBudget.DeepCopy()
+		newObj.Finalizers = []string{"test"}
+
+		response, err := checker.check(&checkRequest{
+			newObj:          newObj,
+			oldObj:          overBudget,
+			client:          fakeClient,
+			newIngressClass: &ingressClass{name: "private", valid: true},
+			oldIngressClass: &ingressClass{name: "private", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+
+	t.Run("Should allow an update of an object being deleted", func(t *testing.T) {
+		newObj := overBudget.DeepCopy()
+		newObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		response, err := checker.check(&checkRequest{newObj: newObj, client: fakeClient})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-global-ratelimit-operator/pkg/rlsparser"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// rateLimitKeySuffixRegex matches the optional suffix of a genericKey following the "namespace.name." prefix.
+// The suffix can not contain dots, so a key can only be decomposed into a single namespace and name.
+var rateLimitKeySuffixRegex = regexp.MustCompile(`^[A-Za-z0-9_-]*$`)
+
+// rateLimitUnitSeconds maps the units accepted by the rlsparser to their length in seconds.
+var rateLimitUnitSeconds = map[string]int64{"s": 1, "m": 60, "h": 3600, "d": 86400}
+
+type checkRateLimitDescriptors struct {
+	next                 checker
+	mode                 string
+	maxRequestsPerSecond int64
+}
+
+//nolint:varnamelen
+func (crld checkRateLimitDescriptors) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	if isPolicyExempt(cr) {
+		return checkNextWithWarnings(crld.next, cr, nil)
+	}
+
+	// The keys are checked on the route descriptors regardless of the parsing, as the rlsparser also fails on the keys
+	// of other objects, e.g. "other.echo.limit", which must be denied even if the rlsValidator only warns.
+	violations := getRateLimitKeyViolations(cr.newObj)
+
+	hasRateLimit, policy, err := rlsparser.ParseGlobalRateLimit(cr.newObj)
+
+	// The rates of the descriptors which can not be parsed are reported by the rlsValidator.
+	if err != nil || !hasRateLimit {
+		return reportViolations(crld.mode, crld.next, cr, violations, nil)
+	}
+
+	// The rlsparser attaches the rate of the genericKey to the top level descriptor or to the nested one if the
+	// descriptor has a second entry, so the rates applied by the rate limit service are checked.
+	for _, descriptor := range policy.RateLimitsDescriptors {
+		violations = crld.appendRateViolation(violations, descriptor.Key, descriptor.RateLimit.RequestsPerUnit, descriptor.RateLimit.Unit)
+
+		for _, nested := range descriptor.Descriptors {
+			violations = crld.appendRateViolation(violations, descriptor.Key, nested.RateLimit.RequestsPerUnit, nested.RateLimit.Unit)
+		}
+	}
+
+	return reportViolations(crld.mode, crld.next, cr, violations, nil)
+}
+
+func (crld *checkRateLimitDescriptors) setNext(c checker) {
+	crld.next = c
+}
+
+// appendRateViolation appends a violation if the rate does not allow any request or exceeds maxRequestsPerSecond.
+func (crld checkRateLimitDescriptors) appendRateViolation(violations []string, key, requestsPerUnit, unit string) []string {
+	if requestsPerUnit == "" {
+		return violations
+	}
+
+	requests, err := strconv.ParseInt(requestsPerUnit, 10, 64)
+	if err != nil || requests == 0 {
+		return append(violations, fmt.Sprintf("rate limit key %s: must allow at least 1 request per unit", key))
+	}
+
+	if crld.maxRequestsPerSecond > 0 && requests > crld.maxRequestsPerSecond*rateLimitUnitSeconds[unit] {
+		return append(violations, fmt.Sprintf("rate limit key %s: %d/%s exceeds %d requests per second",
+			key, requests, unit, crld.maxRequestsPerSecond))
+	}
+
+	return violations
+}
+
+// getRateLimitKeyViolations checks every genericKey of the global rate limit descriptors follows the
+// "namespace.name.suffix" convention, where the suffix is optional.
+func getRateLimitKeyViolations(httpproxy *contourv1.HTTPProxy) []string {
+	violations := make([]string, 0)
+	prefix := fmt.Sprintf("%s.%s.", httpproxy.Namespace, httpproxy.Name)
+
+	for i, route := range httpproxy.Spec.Routes {
+		if route.RateLimitPolicy == nil || route.RateLimitPolicy.Global == nil {
+			continue
+		}
+
+		for j, descriptor := range route.RateLimitPolicy.Global.Descriptors {
+			for k, entry := range descriptor.Entries {
+				if entry.GenericKey == nil {
+					continue
+				}
+
+				suffix, found := strings.CutPrefix(entry.GenericKey.Key, prefix)
+				if !found || !rateLimitKeySuffixRegex.MatchString(suffix) {
+					violations = append(violations, fmt.Sprintf(
+						"spec.routes[%d].rateLimitPolicy.global.descriptors[%d].entries[%d].genericKey.key: %s must be in the form of %s<suffix>, where the suffix is optional and can only contain alphanumeric characters, '-' and '_'",
+						i, j, k, entry.GenericKey.Key, prefix))
+				}
+			}
+		}
+	}
+
+	return violations
+}
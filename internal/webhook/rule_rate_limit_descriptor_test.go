@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckRateLimitDescriptors(t *testing.T) {
+	checker := &checkRateLimitDescriptors{mode: config.RuleModeEnforce, maxRequestsPerSecond: 10}
+
+	newHttpproxy := func(name, key, value string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: name},
+			Spec: contourv1.HTTPProxySpec{Routes: []contourv1.Route{{
+				RateLimitPolicy: &contourv1.RateLimitPolicy{Global: &contourv1.GlobalRateLimitPolicy{
+					Descriptors: []contourv1.RateLimitDescriptor{{Entries: []contourv1.RateLimitDescriptorEntry{
+						{GenericKey: &contourv1.GenericKeyDescriptor{Key: key, Value: value}},
+					}}},
+				}},
+			}}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		httpproxy *contourv1.HTTPProxy
+		allowed   bool
+	}{
+		{name: "Should allow a key with a suffix", httpproxy: newHttpproxy("echo", "test.echo.limit1", "60/m"), allowed: true},
+		{name: "Should allow a key without a suffix", httpproxy: newHttpproxy("echo", "test.echo.", "10/s"), allowed: true},
+		{name: "Should deny a suffix containing dots", httpproxy: newHttpproxy("echo", "test.echo.api.limit", "1/s"), allowed: false},
+		{name: "Should deny a key of another object with a dotted name", httpproxy: newHttpproxy("echo", "test.echo.v2.limit", "1/s"), allowed: false},
+		{name: "Should deny a rate not allowing any request", httpproxy: newHttpproxy("echo", "test.echo.limit", "0/m"), allowed: false},
+		{name: "Should deny a rate exceeding the maximum", httpproxy: newHttpproxy("echo", "test.echo.limit", "601/m"), allowed: false},
+		{name: "Should deny a key of another object even if it can not be parsed", httpproxy: newHttpproxy("echo", "other.echo.limit", "1/s"), allowed: false},
+		{name: "Should leave the rates of unparsable descriptors to the rlsValidator", httpproxy: newHttpproxy("echo", "test.echo.limit", "1/week"), allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := checker.check(&checkRequest{newObj: tt.httpproxy})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+		})
+	}
+
+	nonCompliant := newHttpproxy("echo", "other.echo.limit", "1/s")
+
+	t.Run("Should allow an update not changing the spec of a non-compliant object", func(t *testing.T) {
+		newObj := nonCompliant.DeepCopy()
+		newObj.Finalizers = []string{"test"}
+
+		response, err := checker.check(&checkRequest{
+			newObj:          newObj,
+			oldObj:          nonCompliant,
+			newIngressClass: &ingressClass{name: "private", valid: true},
+			oldIngressClass: &ingressClass{name: "private", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+
+	t.Run("Should allow an update of an object being deleted", func(t *testing.T) {
+		newObj := nonCompliant.DeepCopy()
+		newObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		response, err := checker.check(&checkRequest{newObj: newObj})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type checkRateLimitKeyOwnership struct {
+	next checker
+	mode string
+}
+
+//nolint:varnamelen
+func (crlko checkRateLimitKeyOwnership) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	// The newIngressClass object should be initialized and populated by previous rules.
+	if cr.newIngressClass == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "ingressClass struct is nil"}
+	}
+
+	owner := types.NamespacedName{Namespace: cr.newObj.Namespace, Name: cr.newObj.Name}
+	violations := make([]string, 0)
+	cacheKeys := make([]string, 0)
+
+	for _, key := range getNewRateLimitKeys(cr) {
+		cacheKey := utils.GenerateRateLimitCacheKey(cr.newIngressClass.name, key)
+
+		entry, err := cr.cache.LookupEntry(cacheKey)
+		if err != nil {
+			return nil, &httpErr{code: http.StatusInternalServerError,
+				message: fmt.Sprintf("failed to look up the rate limit key %s: %s", key, err.Error())}
+		}
+
+		if entry != nil && entry.Owner != owner {
+			violations = append(violations, fmt.Sprintf("rate limit key %s is already used by another httpproxy object named %s in namespace %s",
+				key, entry.Owner.Name, entry.Owner.Namespace))
+
+			continue
+		}
+
+		cacheKeys = append(cacheKeys, cacheKey)
+	}
+
+	var dryRun bool
+
+	if cr.dryRun != nil {
+		dryRun = *cr.dryRun
+	}
+
+	reservedKeys := make([]string, 0)
+
+	// Reserve the keys until the controller persists them, so concurrent requests can not acquire the same keys.
+	if len(violations) == 0 && crlko.mode == config.RuleModeEnforce && !dryRun {
+		for _, cacheKey := range cacheKeys {
+			ownerObj, reserved, err := cr.cache.Reserve(cacheKey, &owner, time.Now().Add(time.Duration(entryTtlSecond)*time.Second).Unix())
+			if err != nil {
+				releaseRateLimitKeys(cr, reservedKeys)
+
+				return nil, &httpErr{code: http.StatusInternalServerError,
+					message: fmt.Sprintf("failed to reserve the rate limit key: %s", err.Error())}
+			}
+
+			if reserved {
+				reservedKeys = append(reservedKeys, cacheKey)
+			} else if *ownerObj != owner {
+				violations = append(violations, fmt.Sprintf("rate limit key %s is already used by another httpproxy object named %s in namespace %s",
+					strings.TrimPrefix(cacheKey, utils.GenerateRateLimitCacheKey(cr.newIngressClass.name, "")), ownerObj.Name, ownerObj.Namespace))
+			}
+		}
+	}
+
+	response, err := reportViolations(crlko.mode, crlko.next, cr, violations, nil)
+
+	// The keys reserved by a request denied by this or a later rule, e.g. the FQDN rule, would otherwise block the
+	// legitimate owner until they expire.
+	if err != nil || response == nil || !response.Allowed {
+		releaseRateLimitKeys(cr, reservedKeys)
+	}
+
+	return response, err
+}
+
+// releaseRateLimitKeys deletes the rate limit keys reserved by the request.
+func releaseRateLimitKeys(cr *checkRequest, cacheKeys []string) {
+	for _, cacheKey := range cacheKeys {
+		cr.cache.Delete(cacheKey)
+	}
+}
+
+func (crlko *checkRateLimitKeyOwnership) setNext(c checker) {
+	crlko.next = c
+}
+
+// getNewRateLimitKeys returns the rate limit keys of the new object. On UPDATE operations the keys already used by
+// the old object in the same ingress class are excluded, so the objects sharing keys before enabling the rule are not
+// stuck.
+func getNewRateLimitKeys(cr *checkRequest) []string {
+	keys := utils.GetRateLimitKeys(cr.newObj)
+
+	if cr.oldObj == nil || cr.oldIngressClass == nil || cr.oldIngressClass.name != cr.newIngressClass.name {
+		return keys
+	}
+
+	oldKeys := utils.GetRateLimitKeys(cr.oldObj)
+
+	return slices.DeleteFunc(keys, func(key string) bool {
+		return slices.Contains(oldKeys, key)
+	})
+}
@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/snapp-incubator/contour-admission-webhook/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCheckRateLimitKeyOwnership(t *testing.T) {
+	newHttpproxy := func(name, key string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: name},
+			Spec: contourv1.HTTPProxySpec{Routes: []contourv1.Route{{
+				RateLimitPolicy: &contourv1.RateLimitPolicy{Global: &contourv1.GlobalRateLimitPolicy{
+					Descriptors: []contourv1.RateLimitDescriptor{{Entries: []contourv1.RateLimitDescriptorEntry{
+						{GenericKey: &contourv1.GenericKeyDescriptor{Key: key, Value: "1/s"}},
+					}}},
+				}},
+			}}},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		mode            string
+		newObj          *contourv1.HTTPProxy
+		oldObj          *contourv1.HTTPProxy
+		oldIngressClass *ingressClass
+		allowed         bool
+		warnings        int
+	}{
+		{name: "Should allow an unused key", mode: config.RuleModeEnforce, newObj: newHttpproxy("echo", "test.echo.limit"), allowed: true},
+		{name: "Should allow a key owned by the object", mode: config.RuleModeEnforce, newObj: newHttpproxy("owner", "test.owner.limit"), allowed: true},
+		{name: "Should deny a key owned by another object", mode: config.RuleModeEnforce, newObj: newHttpproxy("echo", "test.owner.limit"), allowed: false},
+		{name: "Should warn on a key owned by another object in warn mode", mode: config.RuleModeWarn, newObj: newHttpproxy("echo", "test.owner.limit"), allowed: true, warnings: 1},
+		{
+			name:            "Should allow an update keeping a key shared with another object",
+			mode:            config.RuleModeEnforce,
+			newObj:          newHttpproxy("echo", "test.owner.limit"),
+			oldObj:          newHttpproxy("echo", "test.owner.limit"),
+			oldIngressClass: &ingressClass{name: "private", valid: true},
+			allowed:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testCache := cache.NewCache(time.Minute)
+			testCache.Set(utils.GenerateRateLimitCacheKey("private", "test.owner.limit"),
+				&types.NamespacedName{Namespace: "test", Name: "owner"}, 0)
+
+			checker := &checkRateLimitKeyOwnership{mode: tt.mode}
+
+			response, err := checker.check(&checkRequest{
+				newObj:          tt.newObj,
+				oldObj:          tt.oldObj,
+				cache:           testCache,
+				newIngressClass: &ingressClass{name: "private", valid: true},
+				oldIngressClass: tt.oldIngressClass,
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+			assert.Len(t, response.Warnings, tt.warnings)
+
+			if tt.allowed && tt.mode == config.RuleModeEnforce {
+				for _, key := range utils.GetRateLimitKeys(tt.newObj) {
+					assert.True(t, testCache.KeyExists(utils.GenerateRateLimitCacheKey("private", key)))
+				}
+			}
+		})
+	}
+}
+
+// denyingChecker denies every request, standing for a later rule of the chain.
+type denyingChecker struct{}
+
+func (denyingChecker) check(_ *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	return &admissionv1.AdmissionResponse{Allowed: false}, nil
+}
+
+func (denyingChecker) setNext(_ checker) {}
+
+func TestCheckRateLimitKeyOwnershipReleasesOnDenial(t *testing.T) {
+	testCache := cache.NewCache(time.Minute)
+	defer func() { testCache.CleanUpStopChan <- true }()
+
+	httpproxy := &contourv1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "echo"},
+		Spec: contourv1.HTTPProxySpec{Routes: []contourv1.Route{{
+			RateLimitPolicy: &contourv1.RateLimitPolicy{Global: &contourv1.GlobalRateLimitPolicy{
+				Descriptors: []contourv1.RateLimitDescriptor{{Entries: []contourv1.RateLimitDescriptorEntry{
+					{GenericKey: &contourv1.GenericKeyDescriptor{Key: "test.echo.limit", Value: "1/s"}},
+				}}},
+			}},
+		}}},
+	}
+
+	checker := &checkRateLimitKeyOwnership{mode: config.RuleModeEnforce}
+	checker.setNext(denyingChecker{})
+
+	response, err := checker.check(&checkRequest{
+		newObj:          httpproxy,
+		cache:           testCache,
+		newIngressClass: &ingressClass{name: "private", valid: true},
+	})
+
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.False(t, testCache.KeyExists(utils.GenerateRateLimitCacheKey("private", "test.echo.limit")))
+}
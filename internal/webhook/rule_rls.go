@@ -1,19 +1,42 @@
 package webhook
 
 import (
+	"fmt"
+	"net/http"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
 	"github.com/snapp-incubator/contour-global-ratelimit-operator/pkg/rlsparser"
 	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type rlsValidator struct {
 	next checker
+	mode string
 }
 
 func (e *rlsValidator) check(checkrequest *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	if isPolicyExempt(checkrequest) {
+		return checkNextWithWarnings(e.next, checkrequest, nil)
+	}
+
 	// check if there is any error in parsing rls configs in HTTPProxy Object
-	_, _, err := rlsparser.ParseGlobalRateLimit(checkrequest.newObj)
-	if err != nil {
-		return acceptWithWarning(err.Error())
+	causes := getRateLimitCauses(checkrequest.newObj)
+
+	if len(causes) > 0 {
+		if e.mode == config.RuleModeEnforce {
+			return &admissionv1.AdmissionResponse{Allowed: false,
+				Result: &metav1.Status{
+					// http code and message returned to the user
+					Code:    http.StatusForbidden,
+					Message: fmt.Sprintf("Rate Limit Config Error: %d invalid descriptor(s)", len(causes)),
+					Reason:  metav1.StatusReasonInvalid,
+					Details: &metav1.StatusDetails{Causes: causes},
+				}}, nil
+		}
+
+		return acceptWithWarning(causes)
 	}
 
 	if e.next != nil {
@@ -26,3 +49,44 @@ func (e *rlsValidator) check(checkrequest *checkRequest) (*admissionv1.Admission
 func (e *rlsValidator) setNext(c checker) {
 	e.next = c
 }
+
+// getRateLimitCauses parses the global rate limit descriptors one by one, so every invalid descriptor is reported
+// rather than the first one only.
+func getRateLimitCauses(httpproxy *contourv1.HTTPProxy) []metav1.StatusCause {
+	causes := make([]metav1.StatusCause, 0)
+
+	for i, route := range httpproxy.Spec.Routes {
+		if route.RateLimitPolicy == nil || route.RateLimitPolicy.Global == nil {
+			continue
+		}
+
+		for j, descriptor := range route.RateLimitPolicy.Global.Descriptors {
+			if _, _, err := rlsparser.ParseGlobalRateLimit(newSingleDescriptorHttpproxy(httpproxy, descriptor)); err != nil {
+				causes = append(causes, metav1.StatusCause{
+					Type:    metav1.CauseTypeFieldValueInvalid,
+					Message: err.Error(),
+					Field:   fmt.Sprintf("spec.routes[%d].rateLimitPolicy.global.descriptors[%d]", i, j),
+				})
+			}
+		}
+	}
+
+	return causes
+}
+
+// newSingleDescriptorHttpproxy returns a copy of the HTTPProxy object metadata with a single route holding the descriptor.
+func newSingleDescriptorHttpproxy(httpproxy *contourv1.HTTPProxy, descriptor contourv1.RateLimitDescriptor) *contourv1.HTTPProxy {
+	return &contourv1.HTTPProxy{
+		ObjectMeta: httpproxy.ObjectMeta,
+		Spec: contourv1.HTTPProxySpec{
+			IngressClassName: httpproxy.Spec.IngressClassName,
+			Routes: []contourv1.Route{{
+				RateLimitPolicy: &contourv1.RateLimitPolicy{
+					Global: &contourv1.GlobalRateLimitPolicy{
+						Descriptors: []contourv1.RateLimitDescriptor{descriptor},
+					},
+				},
+			}},
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRlsValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		invalid []int
+		allowed bool
+		fields  []string
+	}{
+		{name: "Should allow valid descriptors in enforce mode", mode: config.RuleModeEnforce, allowed: true},
+		{name: "Should deny invalid descriptors in enforce mode", mode: config.RuleModeEnforce, invalid: []int{0, 2}, allowed: false,
+			fields: []string{"spec.routes[0].rateLimitPolicy.global.descriptors[0]", "spec.routes[0].rateLimitPolicy.global.descriptors[2]"}},
+		{name: "Should allow invalid descriptors in warn mode", mode: config.RuleModeWarn, invalid: []int{1}, allowed: true,
+			fields: []string{"spec.routes[0].rateLimitPolicy.global.descriptors[1]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpproxy, err := getHTTPProxyFromYAML("./testdata/httpProxy_rls.yaml")
+			assert.Nil(t, err)
+
+			for _, i := range tt.invalid {
+				httpproxy.Spec.Routes[0].RateLimitPolicy.Global.Descriptors[i].Entries[0].GenericKey.Key = "wrong.name.xx"
+			}
+
+			response, httpErr := (&rlsValidator{mode: tt.mode}).check(&checkRequest{newObj: httpproxy})
+
+			assert.Nil(t, httpErr)
+			assert.Equal(t, tt.allowed, response.Allowed)
+
+			if len(tt.fields) == 0 {
+				assert.Nil(t, response.Result)
+
+				return
+			}
+
+			fields := make([]string, 0)
+			for _, cause := range response.Result.Details.Causes {
+				fields = append(fields, cause.Field)
+			}
+
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
+
+func TestRlsValidatorExempt(t *testing.T) {
+	invalid, err := getHTTPProxyFromYAML("./testdata/httpProxy_rls.yaml")
+	assert.Nil(t, err)
+
+	invalid.Spec.Routes[0].RateLimitPolicy.Global.Descriptors[0].Entries[0].GenericKey.Key = "wrong.name.xx"
+
+	checker := &rlsValidator{mode: config.RuleModeEnforce}
+
+	t.Run("Should allow an update not changing the spec of an object with invalid descriptors", func(t *testing.T) {
+		newObj := invalid.DeepCopy()
+		newObj.Finalizers = []string{"test"}
+
+		response, err := checker.check(&checkRequest{
+			newObj:          newObj,
+			oldObj:          invalid,
+			newIngressClass: &ingressClass{name: "private", valid: true},
+			oldIngressClass: &ingressClass{name: "private", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+
+	t.Run("Should allow an update of an object being deleted", func(t *testing.T) {
+		newObj := invalid.DeepCopy()
+		newObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		response, err := checker.check(&checkRequest{newObj: newObj})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+//+kubebuilder:rbac:groups="",resources=services;endpoints,verbs=get;list;watch
+
+type checkServiceReference struct {
+	next              checker
+	mode              string
+	allowExternalName bool
+}
+
+// serviceReference is a service referenced by the HTTPProxy object along with its field path.
+type serviceReference struct {
+	path    string
+	service contourv1.Service
+}
+
+//nolint:varnamelen
+func (csr checkServiceReference) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	// The services are usually deleted along with the object, so its finalizer updates must not be denied.
+	if isPolicyExempt(cr) {
+		return checkNextWithWarnings(csr.next, cr, nil)
+	}
+
+	if cr.client == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "kubernetes client is nil"}
+	}
+
+	violations := make([]string, 0)
+	warnings := make([]string, 0)
+
+	for _, ref := range getServiceReferences(cr.newObj) {
+		service := &corev1.Service{}
+
+		err := cr.client.Get(context.Background(),
+			types.NamespacedName{Namespace: cr.newObj.Namespace, Name: ref.service.Name}, service)
+		if apierrors.IsNotFound(err) {
+			violations = append(violations, fmt.Sprintf("%s: service %s not found", ref.path, ref.service.Name))
+
+			continue
+		} else if err != nil {
+			logger.Error(err, "failed to get the referenced service", "namespace", cr.newObj.Namespace, "name", ref.service.Name)
+
+			warnings = append(warnings, fmt.Sprintf("%s: service %s could not be verified", ref.path, ref.service.Name))
+
+			continue
+		}
+
+		if service.Spec.Type == corev1.ServiceTypeExternalName && !csr.allowExternalName {
+			return &admissionv1.AdmissionResponse{Allowed: false,
+				Result: &metav1.Status{
+					// http code and message returned to the user
+					Code:    http.StatusForbidden,
+					Message: fmt.Sprintf("%s: service %s of type ExternalName is not allowed", ref.path, ref.service.Name),
+				}}, nil
+		}
+
+		if !hasServicePort(service, ref.service.Port) {
+			violations = append(violations, fmt.Sprintf("%s: service %s has no port %d", ref.path, ref.service.Name, ref.service.Port))
+
+			continue
+		}
+
+		if service.Spec.Type == corev1.ServiceTypeExternalName {
+			continue
+		}
+
+		ready, err := hasReadyEndpoints(cr, service)
+		if err != nil {
+			logger.Error(err, "failed to get the endpoints of the referenced service", "namespace", cr.newObj.Namespace, "name", ref.service.Name)
+
+			continue
+		}
+
+		if !ready {
+			warnings = append(warnings, fmt.Sprintf("%s: service %s has no ready endpoints", ref.path, ref.service.Name))
+		}
+	}
+
+	return reportViolations(csr.mode, csr.next, cr, violations, warnings)
+}
+
+func (csr *checkServiceReference) setNext(c checker) {
+	csr.next = c
+}
+
+// getServiceReferences returns the services referenced by the routes and the tcpproxy of the HTTPProxy object.
+func getServiceReferences(httpproxy *contourv1.HTTPProxy) []serviceReference {
+	refs := make([]serviceReference, 0)
+
+	for i, route := range httpproxy.Spec.Routes {
+		for j, service := range route.Services {
+			refs = append(refs, serviceReference{
+				path:    fmt.Sprintf("spec.routes[%d].services[%d]", i, j),
+				service: service,
+			})
+		}
+	}
+
+	if httpproxy.Spec.TCPProxy != nil {
+		for j, service := range httpproxy.Spec.TCPProxy.Services {
+			refs = append(refs, serviceReference{
+				path:    fmt.Sprintf("spec.tcpproxy.services[%d]", j),
+				service: service,
+			})
+		}
+	}
+
+	return refs
+}
+
+func hasServicePort(service *corev1.Service, port int) bool {
+	for _, servicePort := range service.Spec.Ports {
+		if int(servicePort.Port) == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasReadyEndpoints(cr *checkRequest, service *corev1.Service) (bool, error) {
+	endpoints := &corev1.Endpoints{}
+
+	err := cr.client.Get(context.Background(), types.NamespacedName{Namespace: service.Namespace, Name: service.Name}, endpoints)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
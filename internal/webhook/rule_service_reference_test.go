@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckServiceReference(t *testing.T) {
+	services := []*corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "ready"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "not-ready"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "external"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "example.com", Ports: []corev1.ServicePort{{Port: 443}}},
+		},
+	}
+
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "ready"},
+		Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(clientgoscheme.Scheme).
+		WithObjects(services[0], services[1], services[2], endpoints).
+		Build()
+
+	newHttpproxy := func(services ...contourv1.Service) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "test"},
+			Spec: contourv1.HTTPProxySpec{
+				Routes: []contourv1.Route{{Services: services}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		checker       *checkServiceReference
+		httpproxy     *contourv1.HTTPProxy
+		allowed       bool
+		warningsCount int
+	}{
+		{
+			name:      "Should allow a service with ready endpoints",
+			checker:   &checkServiceReference{mode: config.RuleModeWarn},
+			httpproxy: newHttpproxy(contourv1.Service{Name: "ready", Port: 80}),
+			allowed:   true,
+		},
+		{
+			name:          "Should warn about a missing service in warn mode",
+			checker:       &checkServiceReference{mode: config.RuleModeWarn},
+			httpproxy:     newHttpproxy(contourv1.Service{Name: "missing", Port: 80}),
+			allowed:       true,
+			warningsCount: 1,
+		},
+		{
+			name:      "Should deny a missing port in enforce mode",
+			checker:   &checkServiceReference{mode: config.RuleModeEnforce},
+			httpproxy: newHttpproxy(contourv1.Service{Name: "ready", Port: 8080}),
+			allowed:   false,
+		},
+		{
+			name:          "Should warn about a service without ready endpoints in enforce mode",
+			checker:       &checkServiceReference{mode: config.RuleModeEnforce},
+			httpproxy:     newHttpproxy(contourv1.Service{Name: "not-ready", Port: 80}),
+			allowed:       true,
+			warningsCount: 1,
+		},
+		{
+			name:      "Should deny an ExternalName service when not allowed",
+			checker:   &checkServiceReference{mode: config.RuleModeWarn},
+			httpproxy: newHttpproxy(contourv1.Service{Name: "external", Port: 443}),
+			allowed:   false,
+		},
+		{
+			name:      "Should allow an ExternalName service when allowed",
+			checker:   &checkServiceReference{mode: config.RuleModeWarn, allowExternalName: true},
+			httpproxy: newHttpproxy(contourv1.Service{Name: "external", Port: 443}),
+			allowed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := tt.checker.check(&checkRequest{newObj: tt.httpproxy, client: fakeClient})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+			assert.Len(t, response.Warnings, tt.warningsCount)
+		})
+	}
+
+	t.Run("Should allow an update not changing the spec of an object referencing a missing service in enforce mode", func(t *testing.T) {
+		oldObj := newHttpproxy(contourv1.Service{Name: "missing", Port: 80})
+		oldObj.Finalizers = []string{"test"}
+		newObj := oldObj.DeepCopy()
+		newObj.Finalizers = nil
+
+		response, err := (&checkServiceReference{mode: config.RuleModeEnforce}).check(&checkRequest{
+			newObj:          newObj,
+			oldObj:          oldObj,
+			client:          fakeClient,
+			newIngressClass: &ingressClass{name: "private", valid: true},
+			oldIngressClass: &ingressClass{name: "private", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+
+	t.Run("Should allow an update of an object being deleted in enforce mode", func(t *testing.T) {
+		newObj := newHttpproxy(contourv1.Service{Name: "missing", Port: 80})
+		newObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		response, err := (&checkServiceReference{mode: config.RuleModeEnforce}).check(&checkRequest{newObj: newObj, client: fakeClient})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+}
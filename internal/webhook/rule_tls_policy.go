@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultTLSMinimumProtocolVersion is the minimum TLS protocol version used by Contour if not set.
+const defaultTLSMinimumProtocolVersion = "1.2"
+
+// tlsProtocolVersions maps the TLS protocol versions accepted by Contour to their order.
+var tlsProtocolVersions = map[string]int{
+	"1.2": 2,
+	"1.3": 3,
+}
+
+type checkTLSPolicy struct {
+	next     checker
+	policies map[string]config.TLSPolicy // map[ingressClassName]config.TLSPolicy
+}
+
+//nolint:varnamelen
+func (ctp checkTLSPolicy) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	// The newIngressClass object should be initialized and populated by previous rules.
+	if cr.newIngressClass == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "ingressClass struct is nil"}
+	}
+
+	policy, found := ctp.policies[cr.newIngressClass.name]
+	if !found || isPolicyExempt(cr) {
+		if ctp.next != nil {
+			return ctp.next.check(cr)
+		}
+
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+
+	violations := make([]string, 0)
+
+	if virtualHost := cr.newObj.Spec.VirtualHost; virtualHost != nil {
+		tls := virtualHost.TLS
+
+		switch {
+		case tls == nil && policy.RequireTLS:
+			violations = append(violations, "spec.virtualhost.tls: must be set")
+		case tls != nil && !tls.Passthrough:
+			if policy.MinimumProtocolVersion != "" {
+				version := tls.MinimumProtocolVersion
+				if version == "" {
+					version = defaultTLSMinimumProtocolVersion
+				}
+
+				if tlsProtocolVersions[version] < tlsProtocolVersions[policy.MinimumProtocolVersion] {
+					violations = append(violations,
+						fmt.Sprintf("spec.virtualhost.tls.minimumProtocolVersion: must be at least %s", policy.MinimumProtocolVersion))
+				}
+			}
+
+			if policy.RequireClientValidation && tls.ClientValidation == nil {
+				violations = append(violations, "spec.virtualhost.tls.clientValidation: must be set")
+			}
+		}
+	}
+
+	if policy.ForbidPermitInsecure {
+		for i, route := range cr.newObj.Spec.Routes {
+			if route.PermitInsecure {
+				violations = append(violations, fmt.Sprintf("spec.routes[%d].permitInsecure: must not be set", i))
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &admissionv1.AdmissionResponse{Allowed: false,
+			Result: &metav1.Status{
+				// http code and message returned to the user
+				Code: http.StatusForbidden,
+				Message: fmt.Sprintf("tls policy of ingress class %s is violated: %s",
+					cr.newIngressClass.name, strings.Join(violations, "; ")),
+			}}, nil
+	}
+
+	if ctp.next != nil {
+		return ctp.next.check(cr)
+	}
+
+	return &admissionv1.AdmissionResponse{Allowed: true}, nil
+}
+
+func (ctp *checkTLSPolicy) setNext(c checker) {
+	ctp.next = c
+}
+
+// validateTLSPolicies returns an error if a policy sets a minimum protocol version not accepted by Contour, which
+// would otherwise disable the check silently.
+func validateTLSPolicies(policies []config.TLSPolicy) error {
+	for _, policy := range policies {
+		if policy.MinimumProtocolVersion == "" {
+			continue
+		}
+
+		if _, found := tlsProtocolVersions[policy.MinimumProtocolVersion]; !found {
+			return fmt.Errorf("tls policy of ingress class %s sets unknown minimumProtocolVersion %q",
+				policy.IngressClassName, policy.MinimumProtocolVersion)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckTLSPolicy(t *testing.T) {
+	checker := &checkTLSPolicy{
+		policies: map[string]config.TLSPolicy{
+			"public": {
+				IngressClassName:       "public",
+				RequireTLS:             true,
+				ForbidPermitInsecure:   true,
+				MinimumProtocolVersion: "1.3",
+			},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		ingressClassName string
+		spec             contourv1.HTTPProxySpec
+		allowed          bool
+	}{
+		{
+			name:             "Should allow any object of an ingress class without policy",
+			ingressClassName: "private",
+			spec:             contourv1.HTTPProxySpec{VirtualHost: &contourv1.VirtualHost{Fqdn: "test.local"}},
+			allowed:          true,
+		},
+		{
+			name:             "Should deny a virtualhost without tls",
+			ingressClassName: "public",
+			spec:             contourv1.HTTPProxySpec{VirtualHost: &contourv1.VirtualHost{Fqdn: "test.local"}},
+			allowed:          false,
+		},
+		{
+			name:             "Should deny the default minimum protocol version",
+			ingressClassName: "public",
+			spec: contourv1.HTTPProxySpec{VirtualHost: &contourv1.VirtualHost{Fqdn: "test.local",
+				TLS: &contourv1.TLS{SecretName: "test"}}},
+			allowed: false,
+		},
+		{
+			name:             "Should deny routes with permitInsecure",
+			ingressClassName: "public",
+			spec: contourv1.HTTPProxySpec{
+				VirtualHost: &contourv1.VirtualHost{Fqdn: "test.local",
+					TLS: &contourv1.TLS{SecretName: "test", MinimumProtocolVersion: "1.3"}},
+				Routes: []contourv1.Route{{PermitInsecure: true}},
+			},
+			allowed: false,
+		},
+		{
+			name:             "Should allow a compliant object",
+			ingressClassName: "public",
+			spec: contourv1.HTTPProxySpec{
+				VirtualHost: &contourv1.VirtualHost{Fqdn: "test.local",
+					TLS: &contourv1.TLS{SecretName: "test", MinimumProtocolVersion: "1.3"}},
+				Routes: []contourv1.Route{{}},
+			},
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := checker.check(&checkRequest{
+				newObj:          &contourv1.HTTPProxy{Spec: tt.spec},
+				newIngressClass: &ingressClass{name: tt.ingressClassName, valid: true},
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+		})
+	}
+
+	nonCompliant := &contourv1.HTTPProxy{Spec: contourv1.HTTPProxySpec{VirtualHost: &contourv1.VirtualHost{Fqdn: "test.local"}}}
+
+	t.Run("Should allow an update not changing the spec of a non-compliant object", func(t *testing.T) {
+		newObj := nonCompliant.DeepCopy()
+		newObj.Finalizers = []string{"test"}
+
+		response, err := checker.check(&checkRequest{
+			newObj:          newObj,
+			oldObj:          nonCompliant,
+			newIngressClass: &ingressClass{name: "public", valid: true},
+			oldIngressClass: &ingressClass{name: "public", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+
+	t.Run("Should allow an update of a non-compliant object being deleted", func(t *testing.T) {
+		newObj := nonCompliant.DeepCopy()
+		newObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		newObj.Spec.Routes = []contourv1.Route{{}}
+
+		response, err := checker.check(&checkRequest{
+			newObj:          newObj,
+			oldObj:          nonCompliant,
+			newIngressClass: &ingressClass{name: "public", valid: true},
+			oldIngressClass: &ingressClass{name: "public", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+}
+
+func TestCheckTLSPolicyMessage(t *testing.T) {
+	checker := &checkTLSPolicy{
+		policies: map[string]config.TLSPolicy{
+			"public": {IngressClassName: "public", RequireTLS: true, ForbidPermitInsecure: true},
+		},
+	}
+
+	response, err := checker.check(&checkRequest{
+		newObj: &contourv1.HTTPProxy{Spec: contourv1.HTTPProxySpec{
+			VirtualHost: &contourv1.VirtualHost{Fqdn: "test.local"},
+			Routes:      []contourv1.Route{{PermitInsecure: true}},
+		}},
+		newIngressClass: &ingressClass{name: "public", valid: true},
+	})
+
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "tls policy of ingress class public is violated: spec.virtualhost.tls: must be set; "+
+		"spec.routes[0].permitInsecure: must not be set", response.Result.Message)
+}
+
+func TestValidateTLSPolicies(t *testing.T) {
+	assert.Nil(t, validateTLSPolicies([]config.TLSPolicy{{IngressClassName: "public", MinimumProtocolVersion: "1.3"}, {IngressClassName: "private"}}))
+	assert.NotNil(t, validateTLSPolicies([]config.TLSPolicy{{IngressClassName: "public", MinimumProtocolVersion: "1.1"}}))
+}
@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=projectcontour.io,resources=tlscertificatedelegations,verbs=get;list;watch
+
+const tlsSecretPath = "spec.virtualhost.tls.secretName"
+
+type checkTLSSecret struct {
+	next              checker
+	mode              string
+	expiryWarningDays int
+}
+
+//nolint:varnamelen
+func (cts checkTLSSecret) check(cr *checkRequest) (*admissionv1.AdmissionResponse, *httpErr) {
+	virtualHost := cr.newObj.Spec.VirtualHost
+
+	// A TLS passthrough virtualhost does not reference any secret. The secret is usually deleted along with the object,
+	// so its finalizer updates must not be denied.
+	if virtualHost == nil || virtualHost.TLS == nil || virtualHost.TLS.SecretName == "" || isPolicyExempt(cr) {
+		return checkNextWithWarnings(cts.next, cr, nil)
+	}
+
+	if cr.client == nil {
+		return nil, &httpErr{code: http.StatusInternalServerError,
+			message: "kubernetes client is nil"}
+	}
+
+	violations := make([]string, 0)
+	warnings := make([]string, 0)
+
+	secretKey := types.NamespacedName{Namespace: cr.newObj.Namespace, Name: virtualHost.TLS.SecretName}
+
+	// The secret may live in another namespace if referenced as namespace/name.
+	if namespace, name, found := strings.Cut(virtualHost.TLS.SecretName, "/"); found {
+		secretKey = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+
+	if secretKey.Namespace != cr.newObj.Namespace {
+		delegated, err := isSecretDelegated(cr.client, secretKey, cr.newObj.Namespace)
+		if err != nil {
+			logger.Error(err, "failed to list the tls certificate delegations", "namespace", secretKey.Namespace)
+
+			return checkNextWithWarnings(cts.next, cr,
+				[]string{fmt.Sprintf("%s: secret %s could not be verified", tlsSecretPath, secretKey)})
+		}
+
+		if !delegated {
+			violations = append(violations,
+				fmt.Sprintf("%s: secret %s is not delegated to namespace %s", tlsSecretPath, secretKey, cr.newObj.Namespace))
+
+			return reportViolations(cts.mode, cts.next, cr, violations, warnings)
+		}
+	}
+
+	secret := &corev1.Secret{}
+
+	// The secrets are excluded from the cache of the client, so this is a direct read from the API server.
+	err := cr.client.Get(context.Background(), secretKey, secret)
+	if apierrors.IsNotFound(err) {
+		violations = append(violations, fmt.Sprintf("%s: secret %s not found", tlsSecretPath, secretKey))
+
+		return reportViolations(cts.mode, cts.next, cr, violations, warnings)
+	} else if err != nil {
+		logger.Error(err, "failed to get the tls secret", "secret", secretKey)
+
+		return checkNextWithWarnings(cts.next, cr,
+			[]string{fmt.Sprintf("%s: secret %s could not be verified", tlsSecretPath, secretKey)})
+	}
+
+	certificate, err := parseCertificate(secret)
+	if err != nil {
+		violations = append(violations, fmt.Sprintf("%s: secret %s: %s", tlsSecretPath, secretKey, err.Error()))
+
+		return reportViolations(cts.mode, cts.next, cr, violations, warnings)
+	}
+
+	if err := certificate.VerifyHostname(virtualHost.Fqdn); err != nil {
+		violations = append(violations,
+			fmt.Sprintf("%s: certificate in secret %s does not cover fqdn %s", tlsSecretPath, secretKey, virtualHost.Fqdn))
+	}
+
+	now := time.Now()
+
+	if now.After(certificate.NotAfter) {
+		violations = append(violations,
+			fmt.Sprintf("%s: certificate in secret %s expired at %s", tlsSecretPath, secretKey, certificate.NotAfter.Format(time.RFC3339)))
+	} else if certificate.NotAfter.Sub(now) < time.Duration(cts.expiryWarningDays)*24*time.Hour {
+		warnings = append(warnings,
+			fmt.Sprintf("%s: certificate in secret %s expires at %s", tlsSecretPath, secretKey, certificate.NotAfter.Format(time.RFC3339)))
+	}
+
+	return reportViolations(cts.mode, cts.next, cr, violations, warnings)
+}
+
+func (cts *checkTLSSecret) setNext(c checker) {
+	cts.next = c
+}
+
+// isSecretDelegated checks whether a TLSCertificateDelegation object in the secret's namespace delegates the secret
+// to the target namespace.
+func isSecretDelegated(reader client.Reader, secretKey types.NamespacedName, targetNamespace string) (bool, error) {
+	delegations := &contourv1.TLSCertificateDelegationList{}
+
+	if err := reader.List(context.Background(), delegations, client.InNamespace(secretKey.Namespace)); err != nil {
+		return false, err
+	}
+
+	for _, delegation := range delegations.Items {
+		for _, certificateDelegation := range delegation.Spec.Delegations {
+			if certificateDelegation.SecretName != secretKey.Name {
+				continue
+			}
+
+			for _, namespace := range certificateDelegation.TargetNamespaces {
+				if namespace == "*" || namespace == targetNamespace {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// parseCertificate parses the leaf certificate stored in the tls secret.
+func parseCertificate(secret *corev1.Secret) (*x509.Certificate, error) {
+	data, found := secret.Data[corev1.TLSCertKey]
+	if !found {
+		return nil, fmt.Errorf("%s is not set", corev1.TLSCertKey)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no pem encoded certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// generateCertificate returns a pem encoded self-signed certificate for the given DNS names.
+func generateCertificate(t *testing.T, notAfter time.Time, dnsNames ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCheckTLSSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, clientgoscheme.AddToScheme(scheme))
+	assert.Nil(t, contourv1.AddToScheme(scheme))
+
+	newSecret := func(namespace, name string, cert []byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: cert},
+		}
+	}
+
+	delegation := &contourv1.TLSCertificateDelegation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "delegation"},
+		Spec: contourv1.TLSCertificateDelegationSpec{
+			Delegations: []contourv1.CertificateDelegation{{SecretName: "wildcard", TargetNamespaces: []string{"test"}}},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newSecret("test", "valid", generateCertificate(t, time.Now().AddDate(1, 0, 0), "test.local")),
+			newSecret("test", "expiring", generateCertificate(t, time.Now().AddDate(0, 0, 5), "test.local")),
+			newSecret("shared", "wildcard", generateCertificate(t, time.Now().AddDate(1, 0, 0), "*.local")),
+			newSecret("shared", "not-delegated", generateCertificate(t, time.Now().AddDate(1, 0, 0), "*.local")),
+			delegation,
+		).
+		Build()
+
+	newHttpproxy := func(secretName string) *contourv1.HTTPProxy {
+		return &contourv1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "test"},
+			Spec: contourv1.HTTPProxySpec{
+				VirtualHost: &contourv1.VirtualHost{
+					Fqdn: "test.local",
+					TLS:  &contourv1.TLS{SecretName: secretName},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		mode          string
+		secretName    string
+		allowed       bool
+		warningsCount int
+	}{
+		{name: "Should allow a valid certificate", mode: config.RuleModeEnforce, secretName: "valid", allowed: true},
+		{name: "Should warn about an expiring certificate", mode: config.RuleModeEnforce, secretName: "expiring", allowed: true, warningsCount: 1},
+		{name: "Should deny a missing secret in enforce mode", mode: config.RuleModeEnforce, secretName: "missing", allowed: false},
+		{name: "Should warn about a missing secret in warn mode", mode: config.RuleModeWarn, secretName: "missing", allowed: true, warningsCount: 1},
+		{name: "Should allow a delegated secret", mode: config.RuleModeEnforce, secretName: "shared/wildcard", allowed: true},
+		{name: "Should deny a secret which is not delegated", mode: config.RuleModeEnforce, secretName: "shared/not-delegated", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &checkTLSSecret{mode: tt.mode, expiryWarningDays: 30}
+
+			response, err := checker.check(&checkRequest{newObj: newHttpproxy(tt.secretName), client: fakeClient})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, response.Allowed)
+			assert.Len(t, response.Warnings, tt.warningsCount)
+		})
+	}
+
+	t.Run("Should allow an update not changing the spec of an object referencing a missing secret in enforce mode", func(t *testing.T) {
+		oldObj := newHttpproxy("missing")
+		oldObj.Finalizers = []string{"test"}
+		newObj := oldObj.DeepCopy()
+		newObj.Finalizers = nil
+
+		response, err := (&checkTLSSecret{mode: config.RuleModeEnforce}).check(&checkRequest{
+			newObj:          newObj,
+			oldObj:          oldObj,
+			client:          fakeClient,
+			newIngressClass: &ingressClass{name: "private", valid: true},
+			oldIngressClass: &ingressClass{name: "private", valid: true},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+
+	t.Run("Should allow an update of an object being deleted in enforce mode", func(t *testing.T) {
+		newObj := newHttpproxy("missing")
+		newObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		response, err := (&checkTLSSecret{mode: config.RuleModeEnforce}).check(&checkRequest{newObj: newObj, client: fakeClient})
+
+		assert.Nil(t, err)
+		assert.True(t, response.Allowed)
+	})
+}
@@ -6,8 +6,12 @@ import (
 
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/snapp-incubator/contour-admission-webhook/internal/cache"
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
 	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type checker interface {
@@ -19,7 +23,9 @@ type checkRequest struct {
 	newObj          *contourv1.HTTPProxy
 	oldObj          *contourv1.HTTPProxy
 	dryRun          *bool
-	cache           *cache.Cache
+	cache           cache.Cache
+	client          client.Reader
+	userInfo        authenticationv1.UserInfo
 	newIngressClass *ingressClass
 	oldIngressClass *ingressClass
 }
@@ -30,7 +36,7 @@ type ingressClass struct {
 }
 
 //nolint:varnamelen
-func validateV1(ar admissionv1.AdmissionReview, cache *cache.Cache) (*admissionv1.AdmissionResponse, *httpErr) {
+func validateV1(ar admissionv1.AdmissionReview, cache cache.Cache, client client.Reader) (*admissionv1.AdmissionResponse, *httpErr) {
 	contourv1HttpproxyResource := metav1.GroupVersionResource{Group: "projectcontour.io", Version: "v1", Resource: "httpproxies"}
 
 	if ar.Request.Resource != contourv1HttpproxyResource {
@@ -52,23 +58,26 @@ func validateV1(ar admissionv1.AdmissionReview, cache *cache.Cache) (*admissionv
 	}
 
 	cr := &checkRequest{
-		newObj: httpproxy,
-		oldObj: httpproxyOld,
-		dryRun: ar.Request.DryRun,
-		cache:  cache,
+		newObj:   httpproxy,
+		oldObj:   httpproxyOld,
+		dryRun:   ar.Request.DryRun,
+		cache:    cache,
+		client:   client,
+		userInfo: ar.Request.UserInfo,
 	}
-	warningValidatorRlsRules := rlsValidator{}
 
 	switch ar.Request.Operation {
 	case admissionv1.Create:
 		cicnoc := checkIngressClassNameOnCreate{}
 		cfoc := checkFqdnOnCreate{}
 
-		cicnoc.setNext(&cfoc)
+		checkers := append([]checker{&cicnoc}, newPolicyCheckers()...)
+		// The FQDN rule must be the last one in the chain as it reserves the FQDN in the cache.
+		chain(append(checkers, &cfoc)...)
 
 		response, err := cicnoc.check(cr)
 		//warning rules
-		response, err = validateWarningRules(response, err, cr, &warningValidatorRlsRules)
+		response, err = validateWarningRules(response, err, cr, newWarningCheckers()...)
 
 		return response, err
 
@@ -76,11 +85,13 @@ func validateV1(ar admissionv1.AdmissionReview, cache *cache.Cache) (*admissionv
 		cicnou := checkIngressClassNameOnUpdate{}
 		cfou := checkFqdnOnUpdate{}
 
-		cicnou.setNext(&cfou)
+		checkers := append([]checker{&cicnou}, newPolicyCheckers()...)
+		// The FQDN rule must be the last one in the chain as it reserves the FQDN in the cache.
+		chain(append(checkers, &cfou)...)
 
 		response, err := cicnou.check(cr)
 		//warning rules
-		response, err = validateWarningRules(response, err, cr, &warningValidatorRlsRules)
+		response, err = validateWarningRules(response, err, cr, newWarningCheckers()...)
 
 		return response, err
 
@@ -98,3 +109,146 @@ func validateV1(ar admissionv1.AdmissionReview, cache *cache.Cache) (*admissionv
 	return nil, &httpErr{code: http.StatusBadRequest,
 		message: "operation being performed on the requested resource must be one of CREATE, UPDATE or DELETE"}
 }
+
+// chain links the checkers in the given order.
+func chain(checkers ...checker) {
+	for i := 0; i < len(checkers)-1; i++ {
+		checkers[i].setNext(checkers[i+1])
+	}
+}
+
+// isSpecUnchanged checks whether an UPDATE operation changes neither the spec nor the ingressClassName.
+func isSpecUnchanged(cr *checkRequest) bool {
+	if cr.oldIngressClass == nil || cr.oldObj == nil {
+		return false
+	}
+
+	return cr.oldIngressClass.name == cr.newIngressClass.name && apiequality.Semantic.DeepEqual(cr.oldObj.Spec, cr.newObj.Spec)
+}
+
+// isPolicyExempt checks whether the request is exempt from the policy rules. Updates not changing the spec, e.g. adding
+// or removing finalizers by the controller, and updates of objects being deleted must not be denied, otherwise existing
+// objects violating a newly enabled rule could never be deleted.
+func isPolicyExempt(cr *checkRequest) bool {
+	return cr.newObj.DeletionTimestamp != nil || isSpecUnchanged(cr)
+}
+
+// newPolicyCheckers returns the configurable rules applied on CREATE and UPDATE operations.
+func newPolicyCheckers() []checker {
+	checkers := make([]checker, 0)
+
+	if len(rulesConfig.IngressClassAccess) > 0 {
+		accesses := make(map[string]config.IngressClassAccess, len(rulesConfig.IngressClassAccess))
+
+		for _, access := range rulesConfig.IngressClassAccess {
+			accesses[access.IngressClassName] = access
+		}
+
+		checkers = append(checkers, &checkIngressClassAccess{accesses: accesses})
+	}
+
+	if rulesConfig.ServiceReference.Enabled {
+		checkers = append(checkers, &checkServiceReference{
+			mode:              ruleMode(rulesConfig.ServiceReference.Mode, config.RuleModeWarn),
+			allowExternalName: rulesConfig.ServiceReference.AllowExternalName,
+		})
+	}
+
+	if rulesConfig.TLSSecret.Enabled {
+		checkers = append(checkers, &checkTLSSecret{
+			mode:              ruleMode(rulesConfig.TLSSecret.Mode, config.RuleModeWarn),
+			expiryWarningDays: rulesConfig.TLSSecret.ExpiryWarningDays,
+		})
+	}
+
+	if len(rulesConfig.TLSPolicies) > 0 {
+		policies := make(map[string]config.TLSPolicy, len(rulesConfig.TLSPolicies))
+
+		for _, policy := range rulesConfig.TLSPolicies {
+			policies[policy.IngressClassName] = policy
+		}
+
+		checkers = append(checkers, &checkTLSPolicy{policies: policies})
+	}
+
+	if len(rulesConfig.Bounds.Policies) > 0 {
+		policies := make(map[string]config.BoundsPolicy, len(rulesConfig.Bounds.Policies))
+
+		for _, policy := range rulesConfig.Bounds.Policies {
+			policies[policy.IngressClassName] = policy
+		}
+
+		checkers = append(checkers, &checkBounds{
+			mode:     ruleMode(rulesConfig.Bounds.Mode, config.RuleModeWarn),
+			policies: policies,
+		})
+	}
+
+	if rulesConfig.HostRewrite.Enabled {
+		checkers = append(checkers, &checkHostRewrite{
+			allowedHosts:      rulesConfig.HostRewrite.AllowedHosts,
+			domainDelegations: rulesConfig.HostRewrite.DomainDelegations,
+		})
+	}
+
+	if ruleMode(rulesConfig.RateLimit.Mode, config.RuleModeWarn) == config.RuleModeEnforce {
+		checkers = append(checkers, &rlsValidator{mode: config.RuleModeEnforce})
+	}
+
+	if rulesConfig.RateLimit.Descriptors.Enabled {
+		checkers = append(checkers, &checkRateLimitDescriptors{
+			mode:                 ruleMode(rulesConfig.RateLimit.Descriptors.Mode, config.RuleModeEnforce),
+			maxRequestsPerSecond: rulesConfig.RateLimit.Descriptors.MaxRequestsPerSecond,
+		})
+	}
+
+	if rulesConfig.RateLimit.KeyOwnership.Enabled {
+		checkers = append(checkers, &checkRateLimitKeyOwnership{
+			mode: ruleMode(rulesConfig.RateLimit.KeyOwnership.Mode, config.RuleModeEnforce),
+		})
+	}
+
+	if len(rateLimitBudgetPolicies) > 0 || rulesConfig.RateLimit.Budgets.DefaultMaxRequestsPerSecond > 0 {
+		checkers = append(checkers, &checkRateLimitBudget{
+			mode:                        ruleMode(rulesConfig.RateLimit.Budgets.Mode, config.RuleModeEnforce),
+			policies:                    rateLimitBudgetPolicies,
+			defaultMaxRequestsPerSecond: rulesConfig.RateLimit.Budgets.DefaultMaxRequestsPerSecond,
+		})
+	}
+
+	if rulesConfig.RateLimit.Local.Enabled {
+		policies := make(map[string]config.LocalRateLimitPolicy, len(rulesConfig.RateLimit.Local.Policies))
+
+		for _, policy := range rulesConfig.RateLimit.Local.Policies {
+			policies[policy.IngressClassName] = policy
+		}
+
+		checkers = append(checkers, &checkLocalRateLimit{
+			mode:          ruleMode(rulesConfig.RateLimit.Local.Mode, config.RuleModeEnforce),
+			policies:      policies,
+			maxBurstRatio: localRateLimitMaxBurstRatio(rulesConfig.RateLimit.Local.MaxBurstRatio),
+		})
+	}
+
+	return checkers
+}
+
+// newWarningCheckers returns the rules applied on CREATE and UPDATE operations which only return warnings.
+func newWarningCheckers() []checker {
+	checkers := make([]checker, 0)
+
+	if ruleMode(rulesConfig.RateLimit.Mode, config.RuleModeWarn) == config.RuleModeWarn {
+		checkers = append(checkers, &rlsValidator{mode: config.RuleModeWarn})
+	}
+
+	return checkers
+}
+
+// ruleMode returns the configured mode of a rule or the default one if not set.
+func ruleMode(mode, defaultMode string) string {
+	if mode == "" {
+		return defaultMode
+	}
+
+	return mode
+}
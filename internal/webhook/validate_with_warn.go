@@ -3,24 +3,31 @@ package webhook
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func validateWarningRules(response *admissionv1.AdmissionResponse, err *httpErr, request *checkRequest, checkers ...checker) (*admissionv1.AdmissionResponse, *httpErr) {
 	//If the request is rejected during rule checking, then do not process the warning rules.
-	if !response.Allowed {
+	if response == nil || !response.Allowed {
 		return response, err
 	}
 
-	msg := make([]string, 0)
+	// Keep the warnings reported by the rules in the chain.
+	msg := append(make([]string, 0), response.Warnings...)
+	causes := make([]metav1.StatusCause, 0)
 	// Combine all warining msg together
 	for _, c := range checkers {
 		resp, _ := c.check(request)
 		if len(resp.Warnings) > 0 {
 			msg = append(msg, resp.Warnings...)
 		}
+		if resp.Result != nil && resp.Result.Details != nil {
+			causes = append(causes, resp.Result.Details.Causes...)
+		}
 	}
 
 	if wariningResponseCount := len(msg); wariningResponseCount == 0 {
@@ -28,22 +35,61 @@ func validateWarningRules(response *admissionv1.AdmissionResponse, err *httpErr,
 		return &admissionv1.AdmissionResponse{Allowed: true}, nil
 	}
 	//return all warnings
-	return &admissionv1.AdmissionResponse{Allowed: true, Warnings: msg, Result: &metav1.Status{
+	result := &metav1.Status{
 		Code:    http.StatusAccepted,
 		Message: fmt.Sprint(msg),
-	}}, nil
+	}
+	if len(causes) > 0 {
+		result.Details = &metav1.StatusDetails{Causes: causes}
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true, Warnings: msg, Result: result}, nil
 }
 
-func acceptWithWarning(message string) (*admissionv1.AdmissionResponse, *httpErr) {
-	message = fmt.Sprint("Rate Limit Config Error: ", message)
+// acceptWithWarning allows the request and returns a warning per invalid rate limit descriptor. The causes are also
+// attached to the response status, so the clients can process them.
+func acceptWithWarning(causes []metav1.StatusCause) (*admissionv1.AdmissionResponse, *httpErr) {
+	warnings := make([]string, 0, len(causes))
 
-	messageMaxLenth := 120
-	if lenMsg := len(message); lenMsg < 120 {
-		messageMaxLenth = lenMsg
+	for _, cause := range causes {
+		warnings = append(warnings, fmt.Sprint("Rate Limit Config Error: ", cause.Field, ": ", cause.Message))
 	}
 
-	return &admissionv1.AdmissionResponse{Allowed: true, Warnings: []string{message[:messageMaxLenth]}, Result: &metav1.Status{
+	return &admissionv1.AdmissionResponse{Allowed: true, Warnings: warnings, Result: &metav1.Status{
 		Code:    http.StatusAccepted,
-		Message: message,
+		Message: strings.Join(warnings, "; "),
+		Details: &metav1.StatusDetails{Causes: causes},
 	}}, nil
 }
+
+// reportViolations denies the request on any violation if the rule is in enforce mode, otherwise it passes the request
+// to the next checker and attaches both the violations and the warnings as warnings to the response.
+func reportViolations(mode string, next checker, cr *checkRequest, violations, warnings []string) (*admissionv1.AdmissionResponse, *httpErr) {
+	if len(violations) > 0 && mode == config.RuleModeEnforce {
+		return &admissionv1.AdmissionResponse{Allowed: false,
+			Result: &metav1.Status{
+				// http code and message returned to the user
+				Code:    http.StatusForbidden,
+				Message: strings.Join(violations, "; "),
+			}}, nil
+	}
+
+	return checkNextWithWarnings(next, cr, append(violations, warnings...))
+}
+
+// checkNextWithWarnings passes the request to the next checker, if any, and attaches the warnings to the response
+// if the request is allowed.
+func checkNextWithWarnings(next checker, cr *checkRequest, warnings []string) (*admissionv1.AdmissionResponse, *httpErr) {
+	response := &admissionv1.AdmissionResponse{Allowed: true}
+
+	var err *httpErr
+
+	if next != nil {
+		response, err = next.check(cr)
+	}
+
+	if response != nil && response.Allowed && len(warnings) > 0 {
+		response.Warnings = append(warnings, response.Warnings...)
+	}
+
+	return response, err
+}
@@ -8,6 +8,7 @@ import (
 
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
@@ -68,7 +69,7 @@ func Test_validateWarningRules(t *testing.T) {
 
 func Test_acceptWithWarning(t *testing.T) {
 	type args struct {
-		message string
+		causes []metav1.StatusCause
 	}
 	tests := []struct {
 		name string
@@ -77,21 +78,24 @@ func Test_acceptWithWarning(t *testing.T) {
 	}{
 		{
 			name: "msg with value less than 120 char",
-			args: args{message: "test"},
-			want: &admissionv1.AdmissionResponse{Allowed: true, Warnings: []string{"Rate Limit Config Error: test"}},
+			args: args{causes: []metav1.StatusCause{{Field: "spec", Message: "test"}}},
+			want: &admissionv1.AdmissionResponse{Allowed: true, Warnings: []string{"Rate Limit Config Error: spec: test"}},
 		},
 		{
-			name: "msg with value more than 120 should truncate to 120",
-			args: args{message: strings.Repeat("a", 120)},
-			want: &admissionv1.AdmissionResponse{Allowed: true, Warnings: []string{"Rate Limit Config Error: " + strings.Repeat("a", 120-len("Rate Limit Config Error: "))}},
+			name: "msg with value more than 120 should not be truncated",
+			args: args{causes: []metav1.StatusCause{{Field: "spec", Message: strings.Repeat("a", 120)}}},
+			want: &admissionv1.AdmissionResponse{Allowed: true, Warnings: []string{"Rate Limit Config Error: spec: " + strings.Repeat("a", 120)}},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, _ := acceptWithWarning(tt.args.message)
+			got, _ := acceptWithWarning(tt.args.causes)
 			if tt.want.Warnings[0] != got.Warnings[0] {
 				t.Errorf("acceptWithWarning() got = %v, want %v", got, tt.want)
 			}
+			if len(got.Result.Details.Causes) != len(tt.args.causes) {
+				t.Errorf("acceptWithWarning() causes = %v, want %v", got.Result.Details.Causes, tt.args.causes)
+			}
 		})
 	}
 }
@@ -5,6 +5,8 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
@@ -19,6 +21,7 @@ import (
 	apiserver "k8s.io/apiserver/pkg/server"
 	apiserver_options "k8s.io/apiserver/pkg/server/options"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var (
@@ -28,7 +31,12 @@ var (
 
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
 
-	entryTtlSecond int
+	entryTtlSecond      int
+	statelessFqdnLookup bool
+	rulesConfig         config.Rules
+
+	namespaceIngressClassPolicies []namespaceIngressClassPolicy
+	rateLimitBudgetPolicies       []rateLimitBudgetPolicy
 
 	logger = ctrl.Log.WithName("webhook")
 )
@@ -75,10 +83,11 @@ func (so *serverOptions) newServerConfig() *serverConfig {
 	return sc
 }
 
-type admitV1Func func(admissionv1.AdmissionReview, *cache.Cache) (*admissionv1.AdmissionResponse, *httpErr)
+type admitV1Func func(admissionv1.AdmissionReview, cache.Cache, client.Reader) (*admissionv1.AdmissionResponse, *httpErr)
 
 type admissionHandler struct {
-	cache   *cache.Cache
+	cache   cache.Cache
+	client  client.Reader
 	handler admitV1Func
 }
 
@@ -136,7 +145,7 @@ func (ah *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Can not use the already declared err interface
 	// Impossible comparison of interface value with untyped nil
 	// https://staticcheck.dev/docs/checks#SA4023
-	admitResponse, admitHttpError := ah.handler(*admissionReviewRequest, ah.cache)
+	admitResponse, admitHttpError := ah.handler(*admissionReviewRequest, ah.cache, ah.client)
 	if admitHttpError != nil {
 		http.Error(w, admitHttpError.message.(string), admitHttpError.code)
 	}
@@ -163,28 +172,76 @@ func (ah *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func readinessHandler(w http.ResponseWriter, r *http.Request) {
+// readinessHandler reports the webhook ready once the ready function, if set, returns true.
+type readinessHandler struct {
+	ready func() bool
+}
+
+func (rh *readinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
 
-	_, err := w.Write([]byte("ok"))
+	body := "ok"
+
+	if rh.ready != nil && !rh.ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+
+		body = "not ready"
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	_, err := w.Write([]byte(body))
 	if err != nil {
 		logger.Error(err, "error writing the data to the connection as part of an http reply")
 	}
 }
 
-func Setup(cache *cache.Cache) (<-chan struct{}, <-chan struct{}) {
-	// Populate the global variable once to prevent further resource allocations per validation request
+// Setup starts the webhook server. The /readyz endpoint fails until ready returns true, nil means always ready.
+func Setup(cache cache.Cache, client client.Reader, ready func() bool) (<-chan struct{}, <-chan struct{}) {
+	// Populate the global variables once to prevent further resource allocations per validation request
 	cfg := config.GetConfig()
 	entryTtlSecond = cfg.Cache.EntryTtlSecond
+	statelessFqdnLookup = cfg.Cache.Stateless
+	rulesConfig = cfg.Rules
+
+	if err := validateTLSPolicies(cfg.Rules.TLSPolicies); err != nil {
+		panic(err)
+	}
+
+	policies, err := newNamespaceIngressClassPolicies(cfg.Rules.NamespaceIngressClasses)
+	if err != nil {
+		panic(err)
+	}
+
+	namespaceIngressClassPolicies = policies
+
+	budgetPolicies, err := newRateLimitBudgetPolicies(cfg.Rules.RateLimit.Budgets)
+	if err != nil {
+		panic(err)
+	}
+
+	rateLimitBudgetPolicies = budgetPolicies
 
 	serverOptions := newServerOptions(cfg.Webhook.Port, cfg.Webhook.TLSCertFile, cfg.Webhook.TLSKeyFile)
 
 	serverConfig := serverOptions.newServerConfig()
 
 	mux := http.NewServeMux()
-	mux.Handle("/v1/validate", &admissionHandler{cache: cache, handler: validateV1})
-	mux.Handle("/readyz", http.HandlerFunc(readinessHandler))
+	mux.Handle("/v1/validate", &admissionHandler{cache: cache, client: client, handler: validateV1})
+	mux.Handle("/readyz", &readinessHandler{ready: ready})
+
+	if cfg.Webhook.Debug.Enabled {
+		token, err := os.ReadFile(cfg.Webhook.Debug.TokenFile)
+		if err != nil {
+			panic(fmt.Errorf("failed to read the debug token: %w", err))
+		}
+
+		if strings.TrimSpace(string(token)) == "" {
+			panic(fmt.Errorf("debug token file %s is empty", cfg.Webhook.Debug.TokenFile))
+		}
+
+		mux.Handle("/debug/cache", &debugCacheHandler{cache: cache, token: strings.TrimSpace(string(token))})
+	}
 
 	stopCh := apiserver.SetupSignalHandler()
 
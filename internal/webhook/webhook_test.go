@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		ready func() bool
+		code  int
+	}{
+		{name: "Should be ready without a readiness function", code: http.StatusOK},
+		{name: "Should not be ready until the readiness function returns true", ready: func() bool { return false }, code: http.StatusServiceUnavailable},
+		{name: "Should be ready once the readiness function returns true", ready: func() bool { return true }, code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			(&readinessHandler{ready: tt.ready}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.code, recorder.Code)
+		})
+	}
+}
@@ -2,43 +2,175 @@ package utils
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 
 	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/snapp-incubator/contour-admission-webhook/internal/config"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	contourIngressClassAnnotation    = "projectcontour.io/ingress.class"
+	kubernetesIngressClassAnnotation = "kubernetes.io/ingress.class"
+
+	// rateLimitCacheKeyPrefix separates the rate limit key entries from the FQDN entries in the cache,
+	// as a rate limit key may look like an FQDN while neither an FQDN nor an ingressClassName contains ':'.
+	rateLimitCacheKeyPrefix = "ratelimit:"
+
+	// FqdnIndexField is the field index of the HTTPProxy objects on their normalized ingressClassName/FQDN key.
+	FqdnIndexField = "fqdnCacheKey"
 )
 
 var (
-	validIngressClasses *[]string
+	validIngressClasses   *[]string
+	validIngressClassesMu sync.RWMutex
+
+	// defaultIngressClass is populated once on startup by SetDefaultIngressClass to prevent copying the config per call.
+	defaultIngressClass string
 )
 
 func BoolPointer(b bool) *bool {
 	return &b
 }
 
+// GenerateCacheKey returns the ingressClassName/FQDN key of the FQDN cache. As FQDNs are case insensitive and may be
+// fully qualified with a trailing dot, they are lowercased and the trailing dot is removed.
 func GenerateCacheKey(ingressClassName, fqdn string) string {
-	return fmt.Sprintf("%s/%s", ingressClassName, fqdn)
+	return fmt.Sprintf("%s/%s", ingressClassName, strings.ToLower(strings.TrimSuffix(fqdn, ".")))
+}
+
+// GenerateFqdnIndexKey returns the key of the FQDN field index, which is the same as the cache key so both the
+// stateful and the stateless modes consider the same FQDNs equal.
+func GenerateFqdnIndexKey(ingressClassName, fqdn string) string {
+	return GenerateCacheKey(ingressClassName, fqdn)
+}
+
+// IndexFqdn is the indexer function of FqdnIndexField. The objects are indexed regardless of the validity of their
+// ingressClassName, as the valid ingressClassNames may change after the objects are indexed.
+func IndexFqdn(obj client.Object) []string {
+	httpproxy, ok := obj.(*contourv1.HTTPProxy)
+	if !ok || httpproxy.Spec.VirtualHost == nil {
+		return nil
+	}
+
+	return []string{GenerateFqdnIndexKey(GetIngressClassName(httpproxy), httpproxy.Spec.VirtualHost.Fqdn)}
+}
+
+// GenerateRateLimitCacheKey returns the cache key of a global rate limit key. The keys are scoped by the
+// ingressClassName, which is the domain of the rate limit service.
+func GenerateRateLimitCacheKey(ingressClassName, key string) string {
+	return fmt.Sprintf("%s%s/%s", rateLimitCacheKeyPrefix, ingressClassName, key)
+}
+
+// GetRateLimitKeys returns the unique genericKey keys of the global rate limit descriptors.
+func GetRateLimitKeys(httpproxy *contourv1.HTTPProxy) []string {
+	keys := make([]string, 0)
+
+	for _, route := range httpproxy.Spec.Routes {
+		if route.RateLimitPolicy == nil || route.RateLimitPolicy.Global == nil {
+			continue
+		}
+
+		for _, descriptor := range route.RateLimitPolicy.Global.Descriptors {
+			for _, entry := range descriptor.Entries {
+				if entry.GenericKey != nil && entry.GenericKey.Key != "" && !slices.Contains(keys, entry.GenericKey.Key) {
+					keys = append(keys, entry.GenericKey.Key)
+				}
+			}
+		}
+	}
+
+	return keys
 }
 
+// GetIngressClassName resolves the ingressClassName the same way Contour does. In order of precedence:
+// 1. The `projectcontour.io/ingress.class` annotation.
+// 2. The `kubernetes.io/ingress.class` annotation.
+// 3. The `spec.ingressClassName` field.
+// 4. The configured default ingressClassName.
 func GetIngressClassName(httpproxy *contourv1.HTTPProxy) string {
-	ingressClassName := httpproxy.Spec.IngressClassName
+	if _, annotation := getIngressClassAnnotation(httpproxy); annotation != "" {
+		return annotation
+	}
 
-	// For backwards compatibility, when the `kubernetes.io/ingress.class` annotation is set,
-	// it is given precedence over this field.
-	annotation, found := httpproxy.Annotations["kubernetes.io/ingress.class"]
-	if found {
-		ingressClassName = annotation
+	if httpproxy.Spec.IngressClassName != "" {
+		return httpproxy.Spec.IngressClassName
 	}
 
-	return ingressClassName
+	return defaultIngressClass
 }
 
-func ValidateIngressClassName(ingressClassName string) bool {
+// SetDefaultIngressClass sets the default ingressClassName used by GetIngressClassName. It must be called on startup,
+// before the webhook and the controllers run.
+func SetDefaultIngressClass(ingressClassName string) {
+	defaultIngressClass = ingressClassName
+}
+
+// GetIngressClassNameMismatch returns a message if the ingress class annotation and the `spec.ingressClassName` field
+// are both set and disagree, otherwise it returns an empty string.
+func GetIngressClassNameMismatch(httpproxy *contourv1.HTTPProxy) string {
+	key, annotation := getIngressClassAnnotation(httpproxy)
+
+	if annotation == "" || httpproxy.Spec.IngressClassName == "" || annotation == httpproxy.Spec.IngressClassName {
+		return ""
+	}
+
+	return fmt.Sprintf("the %s annotation (%s) takes precedence over spec.ingressClassName (%s)",
+		key, annotation, httpproxy.Spec.IngressClassName)
+}
+
+// getIngressClassAnnotation returns the first ingress class annotation found. Like Contour, the annotation is
+// returned even if empty, in which case the `spec.ingressClassName` field is used.
+func getIngressClassAnnotation(httpproxy *contourv1.HTTPProxy) (string, string) {
+	for _, key := range []string{contourIngressClassAnnotation, kubernetesIngressClassAnnotation} {
+		if annotation, found := httpproxy.Annotations[key]; found {
+			return key, annotation
+		}
+	}
+
+	return "", ""
+}
+
+// GetValidIngressClassNames returns the valid ingressClassNames. Unless set by SetValidIngressClassNames,
+// the configured list is used.
+func GetValidIngressClassNames() []string {
+	validIngressClassesMu.RLock()
+
+	if validIngressClasses != nil {
+		defer validIngressClassesMu.RUnlock()
+
+		return *validIngressClasses
+	}
+
+	validIngressClassesMu.RUnlock()
+
+	validIngressClassesMu.Lock()
+	defer validIngressClassesMu.Unlock()
+
+	// The ingressClassNames may be set meanwhile, e.g. discovered by the IngressClass reconciler, which must not be
+	// overwritten by the configured list.
 	if validIngressClasses == nil {
-		cfg := config.GetConfig()
-		validIngressClasses = &cfg.IngressClasses
+		ingressClassNames := config.GetConfig().IngressClasses
+		validIngressClasses = &ingressClassNames
 	}
 
-	for _, ingressClass := range *validIngressClasses {
+	return *validIngressClasses
+}
+
+// SetValidIngressClassNames replaces the valid ingressClassNames and returns them.
+func SetValidIngressClassNames(ingressClassNames []string) []string {
+	validIngressClassesMu.Lock()
+	defer validIngressClassesMu.Unlock()
+
+	validIngressClasses = &ingressClassNames
+
+	return ingressClassNames
+}
+
+func ValidateIngressClassName(ingressClassName string) bool {
+	for _, ingressClass := range GetValidIngressClassNames() {
 		if ingressClassName == ingressClass {
 			return true
 		}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"testing"
+
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetIngressClassName(t *testing.T) {
+	tests := []struct {
+		name             string
+		annotations      map[string]string
+		ingressClassName string
+		want             string
+		mismatch         bool
+	}{
+		{
+			name:             "Should use spec.ingressClassName without annotations",
+			ingressClassName: "private",
+			want:             "private",
+		},
+		{
+			name:             "Should prefer the kubernetes.io annotation over spec.ingressClassName",
+			annotations:      map[string]string{kubernetesIngressClassAnnotation: "public"},
+			ingressClassName: "private",
+			want:             "public",
+			mismatch:         true,
+		},
+		{
+			name: "Should prefer the projectcontour.io annotation over the kubernetes.io annotation",
+			annotations: map[string]string{
+				contourIngressClassAnnotation:    "public",
+				kubernetesIngressClassAnnotation: "private",
+			},
+			want: "public",
+		},
+		{
+			name: "Should fall back to spec.ingressClassName if the first annotation found is empty",
+			annotations: map[string]string{
+				contourIngressClassAnnotation:    "",
+				kubernetesIngressClassAnnotation: "public",
+			},
+			ingressClassName: "private",
+			want:             "private",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpproxy := &contourv1.HTTPProxy{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       contourv1.HTTPProxySpec{IngressClassName: tt.ingressClassName},
+			}
+
+			assert.Equal(t, tt.want, GetIngressClassName(httpproxy))
+			assert.Equal(t, tt.mismatch, GetIngressClassNameMismatch(httpproxy) != "")
+		})
+	}
+
+	t.Run("Should fall back to the default ingressClassName", func(t *testing.T) {
+		SetDefaultIngressClass("private")
+		defer SetDefaultIngressClass("")
+
+		assert.Equal(t, "private", GetIngressClassName(&contourv1.HTTPProxy{}))
+	})
+}
+
+func TestIndexFqdn(t *testing.T) {
+	httpproxy := &contourv1.HTTPProxy{
+		Spec: contourv1.HTTPProxySpec{
+			IngressClassName: "private",
+			VirtualHost:      &contourv1.VirtualHost{Fqdn: "Echo.Example.com."},
+		},
+	}
+
+	assert.Equal(t, []string{"private/echo.example.com"}, IndexFqdn(httpproxy))
+	assert.Empty(t, IndexFqdn(&contourv1.HTTPProxy{Spec: contourv1.HTTPProxySpec{IngressClassName: "private"}}))
+}
+
+func TestGenerateCacheKey(t *testing.T) {
+	assert.Equal(t, "private/echo.example.com", GenerateCacheKey("private", "Echo.Example.com."))
+	assert.Equal(t, GenerateCacheKey("private", "Echo.Example.com."), GenerateFqdnIndexKey("private", "echo.example.com"))
+	assert.Equal(t, "private/", GenerateCacheKey("private", ""))
+}
+
+func TestGetValidIngressClassNames(t *testing.T) {
+	validIngressClasses = nil
+	defer func() { validIngressClasses = nil }()
+
+	// The configured list is used until the ingressClassNames are set, which it never overwrites.
+	assert.Empty(t, GetValidIngressClassNames())
+
+	SetValidIngressClassNames([]string{"discovered"})
+
+	assert.Equal(t, []string{"discovered"}, GetValidIngressClassNames())
+}